@@ -0,0 +1,388 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Archive of many levels and thumbnails keyed by data ID, each compressed on
+// its own so any of them can be read without the rest:
+//
+//	magic "SMM2ARC" and a version byte
+//	records, levels as CompressLevel and thumbnails as Compress
+//	index, one archiveIndexEntrySize entry per record
+//	trailer: index offset uint64, entry count uint32, index CRC32, magic "SMM2IDX\x00"
+//
+// Appending writes the new records and a new index after the old trailer. If
+// an append is interrupted OpenArchive finds the old trailer again by scanning
+// back from the end, so the archive opens with its old entries.
+
+var (
+	archiveMagic        = []byte("SMM2ARC\x01")
+	archiveTrailerMagic = []byte("SMM2IDX\x00")
+)
+
+const (
+	archiveIndexEntrySize = 25
+	archiveTrailerSize    = 24
+)
+
+type ArchiveEntryKind uint8
+
+const (
+	ARCHIVE_LEVEL     ArchiveEntryKind = 0 // Decrypted level
+	ARCHIVE_THUMBNAIL ArchiveEntryKind = 1
+)
+
+type ArchiveEntry struct {
+	DataId uint64
+	Kind   ArchiveEntryKind
+	Offset int64
+	Size   uint32 // Compressed size
+	CRC    uint32 // CRC32 of the decompressed data
+}
+
+type archiveKey struct {
+	dataId uint64
+	kind   ArchiveEntryKind
+}
+
+type ArchiveWriter struct {
+	w       io.Writer
+	offset  int64
+	entries []ArchiveEntry
+	keys    map[archiveKey]bool
+	closed  bool
+}
+
+// Start a new archive, Close must be called to write the index
+func NewArchiveWriter(w io.Writer) (*ArchiveWriter, error) {
+	_, err := w.Write(archiveMagic)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchiveWriter{w: w, offset: int64(len(archiveMagic)), keys: map[archiveKey]bool{}}, nil
+}
+
+// Add to an existing archive such as an *os.File opened read-write, Close
+// must be called to write the new index
+func AppendArchive(f interface {
+	io.ReaderAt
+	io.WriteSeeker
+}) (*ArchiveWriter, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := OpenArchive(f, size)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &ArchiveWriter{w: f, offset: size, keys: map[archiveKey]bool{}}
+	for _, entry := range archive.entries {
+		w.entries = append(w.entries, entry)
+		w.keys[archiveKey{entry.DataId, entry.Kind}] = true
+	}
+	return w, nil
+}
+
+// Add a decrypted level
+func (w *ArchiveWriter) AddLevel(dataId uint64, decrypted []byte) error {
+	compressed, err := CompressLevel(decrypted)
+	if err != nil {
+		return err
+	}
+	return w.add(archiveKey{dataId, ARCHIVE_LEVEL}, decrypted, compressed)
+}
+
+// Add a thumbnail, encrypted or not
+func (w *ArchiveWriter) AddThumbnail(dataId uint64, thumbnail []byte) error {
+	compressed, err := Compress(thumbnail)
+	if err != nil {
+		return err
+	}
+	return w.add(archiveKey{dataId, ARCHIVE_THUMBNAIL}, thumbnail, compressed)
+}
+
+func (w *ArchiveWriter) add(key archiveKey, data []byte, compressed []byte) error {
+	if w.closed {
+		return fmt.Errorf("archive is closed")
+	}
+	if w.keys[key] {
+		return fmt.Errorf("data id %d is already in the archive", key.dataId)
+	}
+
+	_, err := w.w.Write(compressed)
+	if err != nil {
+		return err
+	}
+	w.entries = append(w.entries, ArchiveEntry{
+		DataId: key.dataId,
+		Kind:   key.kind,
+		Offset: w.offset,
+		Size:   uint32(len(compressed)),
+		CRC:    crc32.ChecksumIEEE(data),
+	})
+	w.keys[key] = true
+	w.offset += int64(len(compressed))
+	return nil
+}
+
+// Write the index and trailer, doesn't close the underlying writer
+func (w *ArchiveWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	index := make([]byte, 0, len(w.entries)*archiveIndexEntrySize+archiveTrailerSize)
+	for _, entry := range w.entries {
+		index = binary.LittleEndian.AppendUint64(index, entry.DataId)
+		index = append(index, byte(entry.Kind))
+		index = binary.LittleEndian.AppendUint64(index, uint64(entry.Offset))
+		index = binary.LittleEndian.AppendUint32(index, entry.Size)
+		index = binary.LittleEndian.AppendUint32(index, entry.CRC)
+	}
+	indexCRC := crc32.ChecksumIEEE(index)
+
+	index = binary.LittleEndian.AppendUint64(index, uint64(w.offset))
+	index = binary.LittleEndian.AppendUint32(index, uint32(len(w.entries)))
+	index = binary.LittleEndian.AppendUint32(index, indexCRC)
+	index = append(index, archiveTrailerMagic...)
+	_, err := w.w.Write(index)
+	return err
+}
+
+type ArchiveReader struct {
+	r       io.ReaderAt
+	entries []ArchiveEntry
+	keys    map[archiveKey]int
+}
+
+// Read only the index of an archive, records are read on demand. When the
+// end of the archive isn't a valid trailer, as after an interrupted append,
+// the last valid trailer before it is used.
+func OpenArchive(r io.ReaderAt, size int64) (*ArchiveReader, error) {
+	if size < int64(len(archiveMagic)+archiveTrailerSize) {
+		return nil, fmt.Errorf("archive too small")
+	}
+	magic := make([]byte, len(archiveMagic))
+	err := readFullAt(r, magic, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, archiveMagic) {
+		return nil, fmt.Errorf("not an archive")
+	}
+
+	a, indexErr := openArchiveIndex(r, size)
+	if indexErr == nil {
+		return a, nil
+	}
+	// Previous trailers are only valid if their magic, range and CRC all
+	// check out, so records that happen to contain the magic are skipped
+	end := size - 1
+	for {
+		end, err = findArchiveTrailer(r, end)
+		if err != nil {
+			return nil, err
+		}
+		if end < 0 {
+			return nil, indexErr
+		}
+		recovered, err := openArchiveIndex(r, end)
+		if err == nil {
+			return recovered, nil
+		}
+		end--
+	}
+}
+
+// Index of the archive whose trailer ends at end
+func openArchiveIndex(r io.ReaderAt, end int64) (*ArchiveReader, error) {
+	if end < int64(len(archiveMagic)+archiveTrailerSize) {
+		return nil, fmt.Errorf("archive too small")
+	}
+	trailer := make([]byte, archiveTrailerSize)
+	err := readFullAt(r, trailer, end-archiveTrailerSize)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(trailer[16:], archiveTrailerMagic) {
+		return nil, fmt.Errorf("archive index missing, was the writer closed?")
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(trailer[0:]))
+	count := int64(binary.LittleEndian.Uint32(trailer[8:]))
+	if indexOffset < int64(len(archiveMagic)) || indexOffset+count*archiveIndexEntrySize != end-archiveTrailerSize {
+		return nil, fmt.Errorf("archive index out of range")
+	}
+
+	index := make([]byte, count*archiveIndexEntrySize)
+	err = readFullAt(r, index, indexOffset)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(index) != binary.LittleEndian.Uint32(trailer[12:]) {
+		return nil, fmt.Errorf("archive index crc invalid")
+	}
+
+	a := &ArchiveReader{r: r, keys: map[archiveKey]int{}}
+	for i := 0; i < int(count); i++ {
+		b := index[i*archiveIndexEntrySize:]
+		entry := ArchiveEntry{
+			DataId: binary.LittleEndian.Uint64(b[0:]),
+			Kind:   ArchiveEntryKind(b[8]),
+			Offset: int64(binary.LittleEndian.Uint64(b[9:])),
+			Size:   binary.LittleEndian.Uint32(b[17:]),
+			CRC:    binary.LittleEndian.Uint32(b[21:]),
+		}
+		if entry.Offset < int64(len(archiveMagic)) || entry.Offset+int64(entry.Size) > indexOffset {
+			return nil, fmt.Errorf("archive entry %d out of range", entry.DataId)
+		}
+		a.keys[archiveKey{entry.DataId, entry.Kind}] = len(a.entries)
+		a.entries = append(a.entries, entry)
+	}
+	return a, nil
+}
+
+// End of the last trailer magic that ends before end, -1 if there is none
+func findArchiveTrailer(r io.ReaderAt, end int64) (int64, error) {
+	const chunkSize = 0x10000
+	first := int64(len(archiveMagic))
+	buf := make([]byte, chunkSize)
+	for end-first >= int64(len(archiveTrailerMagic)) {
+		start := end - chunkSize
+		if start < first {
+			start = first
+		}
+		chunk := buf[:end-start]
+		err := readFullAt(r, chunk, start)
+		if err != nil {
+			return -1, err
+		}
+		if i := bytes.LastIndex(chunk, archiveTrailerMagic); i >= 0 {
+			return start + int64(i+len(archiveTrailerMagic)), nil
+		}
+		if start == first {
+			break
+		}
+		// Overlap so a magic across chunks is still found
+		end = start + int64(len(archiveTrailerMagic)) - 1
+	}
+	return -1, nil
+}
+
+// ReadAt that only fails when p wasn't filled, io.ReaderAt allows io.EOF
+// together with a full read at the end of the input
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Entries in the order they were added
+func (a *ArchiveReader) Entries() []ArchiveEntry {
+	return append([]ArchiveEntry{}, a.entries...)
+}
+
+func (a *ArchiveReader) entry(dataId uint64, kind ArchiveEntryKind) (*ArchiveEntry, error) {
+	i, ok := a.keys[archiveKey{dataId, kind}]
+	if !ok {
+		return nil, fmt.Errorf("data id %d not in archive", dataId)
+	}
+	return &a.entries[i], nil
+}
+
+func (a *ArchiveReader) read(entry *ArchiveEntry) ([]byte, error) {
+	compressed := make([]byte, entry.Size)
+	err := readFullAt(a.r, compressed, entry.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if entry.Kind == ARCHIVE_LEVEL {
+		data, err = DecompressLevel(compressed)
+	} else {
+		data, err = Decompress(compressed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("data id %d: %v", entry.DataId, err)
+	}
+	if crc32.ChecksumIEEE(data) != entry.CRC {
+		return nil, fmt.Errorf("data id %d: crc invalid", entry.DataId)
+	}
+	return data, nil
+}
+
+// Decrypted level, see LoadDecrypted
+func (a *ArchiveReader) Get(dataId uint64) ([]byte, error) {
+	entry, err := a.entry(dataId, ARCHIVE_LEVEL)
+	if err != nil {
+		return nil, err
+	}
+	return a.read(entry)
+}
+
+func (a *ArchiveReader) GetThumbnail(dataId uint64) ([]byte, error) {
+	entry, err := a.entry(dataId, ARCHIVE_THUMBNAIL)
+	if err != nil {
+		return nil, err
+	}
+	return a.read(entry)
+}
+
+// Only decompress the header of a level. The CRC covers the whole level so it
+// isn't checked, use Verify for that.
+func (a *ArchiveReader) GetHeader(dataId uint64) (*Header, error) {
+	entry, err := a.entry(dataId, ARCHIVE_LEVEL)
+	if err != nil {
+		return nil, err
+	}
+
+	section := io.NewSectionReader(a.r, entry.Offset, int64(entry.Size))
+	version := []byte{0}
+	_, err = io.ReadFull(section, version)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := levelDicts[version[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown dictionary version %d", version[0])
+	}
+	reader, err := zlib.NewReaderDict(section, dict)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	buf := make([]byte, headerSize)
+	_, err = io.ReadFull(reader, buf)
+	if err != nil {
+		return nil, fmt.Errorf("data id %d: %v", dataId, err)
+	}
+	h := &Header{}
+	decodeHeader(h, buf)
+	return h, nil
+}
+
+// Decompress every record and check its CRC
+func (a *ArchiveReader) Verify() error {
+	for i := range a.entries {
+		_, err := a.read(&a.entries[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, path string, levels map[uint64][]byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewArchiveWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for dataId, level := range levels {
+		if err := w.AddLevel(dataId, level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openTestArchive(t *testing.T, path string) *ArchiveReader {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := OpenArchive(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// Simulate an append that stopped after writing records and part of the index
+func TestArchiveInterruptedAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels.arc")
+	level := newSyntheticLevel(t, 1)
+	writeTestArchive(t, path, map[uint64][]byte{1: level})
+
+	for _, cut := range []int{0, 1, archiveIndexEntrySize + archiveTrailerSize - 1} {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := AppendArchive(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.AddLevel(uint64(100+cut), newSyntheticLevel(t, 2)); err != nil {
+			t.Fatal(err)
+		}
+		// Write the index to a buffer and only part of it to the file
+		var index bytes.Buffer
+		w.w = &index
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(index.Bytes()[:cut]); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		a := openTestArchive(t, path)
+		if len(a.Entries()) != 1 {
+			t.Fatalf("cut %d: %d entries, want the 1 from before the append", cut, len(a.Entries()))
+		}
+		if got, err := a.Get(1); err != nil || !bytes.Equal(got, level) {
+			t.Fatalf("cut %d: level changed, %v", cut, err)
+		}
+	}
+
+	// Appending after the recovered index keeps the old entries
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := AppendArchive(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddLevel(2, level); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	a := openTestArchive(t, path)
+	if len(a.Entries()) != 2 {
+		t.Fatalf("%d entries after appending", len(a.Entries()))
+	}
+	if err := a.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveNotClosed(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewArchiveWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddLevel(1, newSyntheticLevel(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Errorf("archive without an index opened")
+	}
+}
+
+// ReaderAt that returns io.EOF with every read reaching the end, as allowed
+type eofReaderAt struct {
+	r *bytes.Reader
+}
+
+func (e eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := e.r.ReadAt(p, off)
+	if err == nil && off+int64(n) == e.r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func TestArchiveReadAtEOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels.arc")
+	level := newSyntheticLevel(t, 1)
+	writeTestArchive(t, path, map[uint64][]byte{1: level})
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := OpenArchive(eofReaderAt{bytes.NewReader(buf)}, int64(len(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Get(1); err != nil || !bytes.Equal(got, level) {
+		t.Errorf("level changed, %v", err)
+	}
+	if _, err := OpenArchive(eofReaderAt{bytes.NewReader(buf[:len(buf)-1])}, int64(len(buf))); err == nil {
+		t.Errorf("short archive opened")
+	}
+}
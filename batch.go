@@ -0,0 +1,140 @@
+package smm2_parsing
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+)
+
+// One level for BatchDecode. Data is used if set, then Reader, otherwise Name
+// is read as a file.
+type BatchInput struct {
+	Name   string
+	Data   []byte
+	Reader io.Reader
+}
+
+type BatchOptions struct {
+	Workers   int  // Levels decoded at once, defaults to runtime.NumCPU()
+	Decrypted bool // Inputs are decrypted BCDs
+	Validate  bool // Run BCD.Validate, its error becomes the result's Err
+	Compress  bool // Fill BatchResult.Compressed with the decrypted level passed through Compress
+}
+
+type BatchResult struct {
+	Index      int // Position of the input, results come back in this order
+	Name       string
+	Level      *BCD // Set even if validation failed
+	Compressed []byte
+	Err        error
+}
+
+// Decode levels from inputs on a pool of opts.Workers goroutines. Results come
+// back in input order. The returned channel is closed once inputs is closed and
+// every result was received, or as soon as ctx is done.
+func BatchDecode(ctx context.Context, inputs <-chan BatchInput, opts *BatchOptions) <-chan BatchResult {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	out := make(chan BatchResult)
+	// Results in input order, each filled in by its own worker
+	pending := make(chan chan BatchResult, workers)
+	running := make(chan struct{}, workers)
+
+	go func() {
+		defer close(pending)
+		for index := 0; ; index++ {
+			var input BatchInput
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case input, ok = <-inputs:
+				if !ok {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case running <- struct{}{}:
+			}
+
+			result := make(chan BatchResult, 1)
+			select {
+			case <-ctx.Done():
+				<-running
+				return
+			case pending <- result:
+			}
+			go func(index int, input BatchInput) {
+				defer func() { <-running }()
+				result <- decodeBatchInput(index, input, opts)
+			}(index, input)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+		for result := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case r := <-result:
+				select {
+				case <-ctx.Done():
+					return
+				case out <- r:
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+func decodeBatchInput(index int, input BatchInput, opts *BatchOptions) BatchResult {
+	result := BatchResult{Index: index, Name: input.Name}
+
+	buf := input.Data
+	if buf == nil {
+		if input.Reader != nil {
+			buf, result.Err = io.ReadAll(input.Reader)
+		} else {
+			buf, result.Err = os.ReadFile(input.Name)
+		}
+		if result.Err != nil {
+			return result
+		}
+	}
+
+	level := &BCD{}
+	if opts.Decrypted {
+		result.Err = level.LoadDecrypted(buf)
+	} else {
+		result.Err = level.Load(buf)
+	}
+	if result.Err != nil {
+		return result
+	}
+	result.Level = level
+
+	if opts.Compress {
+		decrypted, _ := level.MarshalBinary()
+		result.Compressed, result.Err = Compress(decrypted)
+		if result.Err != nil {
+			return result
+		}
+	}
+	if opts.Validate {
+		result.Err = level.Validate()
+	}
+	return result
+}
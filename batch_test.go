@@ -0,0 +1,71 @@
+package smm2_parsing
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBatchDecodeOrder(t *testing.T) {
+	levels := make([][]byte, 8)
+	for i := range levels {
+		levels[i] = mustMarshal(t, newTestLevel(t, int64(i)))
+	}
+	inputs := make(chan BatchInput)
+	go func() {
+		defer close(inputs)
+		for _, buf := range levels {
+			inputs <- BatchInput{Data: buf}
+		}
+	}()
+
+	index := 0
+	for result := range BatchDecode(context.Background(), inputs, &BatchOptions{Workers: 3, Decrypted: true}) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if result.Index != index {
+			t.Fatalf("result %d came back as %d", result.Index, index)
+		}
+		index++
+	}
+	if index != len(levels) {
+		t.Errorf("%d results for %d inputs", index, len(levels))
+	}
+}
+
+// Cancelling without reading the results must not leave goroutines behind
+func TestBatchDecodeCancel(t *testing.T) {
+	buf := mustMarshal(t, newTestLevel(t, 1))
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	inputs := make(chan BatchInput)
+	fed := make(chan struct{})
+	go func() {
+		defer close(fed)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case inputs <- BatchInput{Data: buf}:
+			}
+		}
+	}()
+	out := BatchDecode(ctx, inputs, &BatchOptions{Workers: 1, Decrypted: true})
+	// Let the producer fill every pending slot
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-fed
+	for range out {
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after cancelling, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
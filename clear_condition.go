@@ -0,0 +1,186 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+type ClearConInfo struct {
+	Category      ClearConCategory // CATEGORY_NONE if the category isn't known
+	UsesMagnitude bool             // Header.ClearConditionMagnitude is the required count
+}
+
+// Category of every known clear condition. Counted conditions ("at least all")
+// are CATEGORY_PARTS. The category of the rest (holding items, power-ups,
+// wearing, riding and the like) hasn't been checked against levels from the
+// game, so it's left as CATEGORY_NONE instead of a guess.
+//
+// The P switch and POW block conditions share one ID between the counted
+// form and holding the item, the table has the counted form.
+var clearConInfos = map[ClearConId]ClearConInfo{
+	REACH_THE_GOAL_WITHOUT_LANDING_AFTER_LEAVING_THE_GROUND:                                                    {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MECHAKOOPA:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHEEP_CHEEP:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WITHOUT_TAKING_DAMAGE:                                                                       {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_BOOMERANG_MARIO:                                                                          {CATEGORY_NONE, false},
+	REACH_THE_GOAL_WHILE_WEARING_A_SHOE:                                                                        {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_FIRE_MARIO:                                                                               {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_FROG_MARIO:                                                                               {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LARRY:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_RACCOON_MARIO:                                                                            {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BLOOPER:                                                        {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_PROPELLER_MARIO:                                                                          {CATEGORY_NONE, false},
+	REACH_THE_GOAL_WHILE_WEARING_A_PROPELLER_BOX:                                                               {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPIKE:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOOM_BOOM:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_HOLDING_A_KOOPA_SHELL:                                                                 {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PORCUPUFFER:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHARVAARGH:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLET_BILL:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLY_BULLIES:                                                  {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_WEARING_A_GOOMBA_MASK:                                                                 {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_HOP_CHOPS:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_HOLDING_A_RED_POW_BLOCK_OR_REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_RED_POW_BLOCK: {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOB_OMB:                                                        {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPINY_SPINIES:                                                  {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_MEOWSER:                                                 {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANT_TROOPER:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_ON_A_LAKITUS_CLOUD:                                                                          {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOO:                                                            {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROY:                                                            {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_HOLDING_A_TRAMPOLINE:                                                                  {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MORTON:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FISH_BONE:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MONTY_MOLE:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_PICKING_UP_AT_LEAST_ALL_1_UP_MUSHROOM:                                                 {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_HAMMER_BRO:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH:              {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_POW_BLOCK_OR_REACH_THE_GOAL_WHILE_HOLDING_A_POW_BLOCK:         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANGRY_SUN:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POKEY:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_SUPERBALL_MARIO:                                                                          {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POM_POM:                                                        {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PEEPA:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAKITU:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LEMMY:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAVA_BUBBLE:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_WEARING_A_BULLET_BILL_MASK:                                                            {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_BIG_MARIO:                                                                                {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_CAT_MARIO:                                                                                {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA:                                                {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_THWOMP:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_IGGY:                                                           {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_WEARING_A_DRY_BONES_SHELL:                                                             {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SLEDGE_BRO:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROCKY_WRENCH:                                                   {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_50_COIN:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_FLYING_SQUIRREL_MARIO:                                                                    {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_BUZZY_MARIO:                                                                              {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_BUILDER_MARIO:                                                                            {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_CAPE_MARIO:                                                                               {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WENDY:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_WHILE_WEARING_A_CANNON_BOX:                                                                  {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_LINK:                                                                                     {CATEGORY_NONE, false},
+	REACH_THE_GOAL_WHILE_YOU_HAVE_SUPER_STAR_INVINCIBILITY:                                                     {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBRAT_GOOMBUD:                                               {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_10_COIN:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BUZZY_BEETLE:                                                   {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_JR:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_KOOPA_TROOPA:                                                   {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHAIN_CHOMP:                                                    {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MUNCHER:                                                        {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WIGGLER:                                                        {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_SMB2_MARIO:                                                                               {CATEGORY_NONE, false},
+	REACH_THE_GOAL_IN_A_KOOPA_CLOWN_CAR_JUNIOR_CLOWN_CAR:                                                       {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AS_SPINY_MARIO:                                                                              {CATEGORY_NONE, false},
+	REACH_THE_GOAL_IN_A_KOOPA_TROOPA_CAR:                                                                       {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_PLANT_JUMPING_PIRANHA_PLANT:                            {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_DRY_BONES:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_STINGBY_STINGBIES:                                              {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_CREEPER:                                                {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FIRE_PIRANHA_PLANT:                                             {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_BREAKING_AT_LEAST_ALL_CRATES:                                                          {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LUDWIG:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_SUPER_MARIO:                                                                              {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SKIPSQUEAK:                                                     {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_COIN:                                                            {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MAGIKOOPA:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_30_COIN:                                                         {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AS_BALLOON_MARIO:                                                                            {CATEGORY_NONE, false},
+	REACH_THE_GOAL_WHILE_WEARING_A_RED_POW_BOX:                                                                 {CATEGORY_NONE, false},
+	REACH_THE_GOAL_WHILE_RIDING_YOSHI:                                                                          {CATEGORY_NONE, false},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPIKE_TOP:                                                      {CATEGORY_PARTS, true},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BANZAI_BILL:                                                    {CATEGORY_PARTS, true},
+}
+
+// Category and magnitude use of a known clear condition
+func ClearConInfoOf(id ClearConId) (ClearConInfo, bool) {
+	info, ok := clearConInfos[id]
+	return info, ok
+}
+
+// Clear condition IDs are the CRC32 of the condition's internal name
+func ClearConditionCRC(name string) ClearConId {
+	return ClearConId(crc32.ChecksumIEEE([]byte(name)))
+}
+
+// Clear conditions that can also mean holding the item, without a magnitude
+func clearConHasHoldingForm(id ClearConId) bool {
+	return id == REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH ||
+		id == REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_POW_BLOCK_OR_REACH_THE_GOAL_WHILE_HOLDING_A_POW_BLOCK ||
+		id == REACH_THE_GOAL_WHILE_HOLDING_A_RED_POW_BLOCK_OR_REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_RED_POW_BLOCK
+}
+
+// Set the clear condition object, category and magnitude together. Counted
+// conditions need a magnitude of at least 1, others must have 0. For the P
+// switch and POW block conditions a magnitude of 0 means holding the item.
+// Conditions whose category isn't known are an error.
+func (h *Header) SetClearCondition(id ClearConId, magnitude uint16) error {
+	category, err := clearConCategory(id, magnitude)
+	if err != nil {
+		return err
+	}
+	if id != CLEARCON_NONE && category == CATEGORY_NONE {
+		return fmt.Errorf("category of clear condition %s isn't known", id)
+	}
+	h.ClearConditionObject = uint32(id)
+	h.ClearConditionCategory = uint8(category)
+	h.ClearConditionMagnitude = magnitude
+	return nil
+}
+
+func clearConCategory(id ClearConId, magnitude uint16) (ClearConCategory, error) {
+	if id == CLEARCON_NONE {
+		if magnitude != 0 {
+			return 0, fmt.Errorf("no clear condition but magnitude %d", magnitude)
+		}
+		return CATEGORY_NONE, nil
+	}
+
+	info, ok := clearConInfos[id]
+	if !ok {
+		return 0, fmt.Errorf("unknown clear condition %d", id)
+	}
+	if clearConHasHoldingForm(id) && magnitude == 0 {
+		return CATEGORY_NONE, nil
+	}
+	if info.UsesMagnitude && magnitude == 0 {
+		return 0, fmt.Errorf("clear condition %s needs a magnitude", id)
+	}
+	if !info.UsesMagnitude && magnitude != 0 {
+		return 0, fmt.Errorf("clear condition %s doesn't use a magnitude, got %d", id, magnitude)
+	}
+	return info.Category, nil
+}
+
+// Check the magnitude fits the clear condition, see SetClearCondition. The
+// category isn't checked since the table doesn't know all of them, and
+// unknown conditions are not an error.
+func (h *Header) validateClearCondition() error {
+	id := ClearConId(h.ClearConditionObject)
+	if _, ok := clearConInfos[id]; !ok && id != CLEARCON_NONE {
+		return nil
+	}
+	_, err := clearConCategory(id, h.ClearConditionMagnitude)
+	return err
+}
@@ -0,0 +1,228 @@
+package smm2_parsing
+
+import "fmt"
+
+// Objects a clear condition needs, any of objects counts once per object or
+// once per block (or other container) holding one. Spawners hold an unlimited
+// amount: pipes with the object inside, blasters for bullets and so on.
+type clearConRequirement struct {
+	objects  []ObjId
+	spawners []ObjId
+	styles   []GameStyle // Styles the condition is possible in, nil for all
+}
+
+func objs(ids ...ObjId) []ObjId {
+	return ids
+}
+
+func styles(s ...GameStyle) []GameStyle {
+	return s
+}
+
+var allStyles = styles(STYLE_M1, STYLE_M3, STYLE_MW, STYLE_WU, STYLE_3W)
+
+// Conditions missing here are not checked. Power-ups whose object isn't known
+// only have their style checked. Every power-up condition lists its styles,
+// Buzzy and Spiny Mario are shell helmets and aren't checked.
+var clearConRequirements = map[ClearConId]clearConRequirement{
+	REACH_THE_GOAL_WITHOUT_LANDING_AFTER_LEAVING_THE_GROUND: {},
+	REACH_THE_GOAL_WITHOUT_TAKING_DAMAGE:                    {},
+
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA:                     {objects: objs(GOOMBA, SHOE_GOOMBA)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_KOOPA_TROOPA:                        {objects: objs(KOOPA)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_PLANT_JUMPING_PIRANHA_PLANT: {objects: objs(PIRANHA_FLOWER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FIRE_PIRANHA_PLANT:                  {objects: objs(PIRANHA_FLOWER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_HAMMER_BRO:                          {objects: objs(HAMMER_BRO)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SLEDGE_BRO:                          {objects: objs(HAMMER_BRO)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOB_OMB:                             {objects: objs(BOB_OMB)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPINY_SPINIES:                       {objects: objs(SPINY), spawners: objs(LAKITU)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BUZZY_BEETLE:                        {objects: objs(BUZZY_BEETLE)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAKITU:                              {objects: objs(LAKITU)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLET_BILL:                         {spawners: objs(BULLET_BILL_BLASTER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BANZAI_BILL:                         {objects: objs(BANZAI_BILL), spawners: objs(BULLET_BILL_BLASTER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MAGIKOOPA:                           {objects: objs(MAGIKOOPA)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPIKE_TOP:                           {objects: objs(SPIKE_TOP)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOO:                                 {objects: objs(BOO)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_DRY_BONES:                           {objects: objs(DRY_BONES)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BLOOPER:                             {objects: objs(BLOOPER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SKIPSQUEAK:                          {objects: objs(SKIPSQUEAK)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WIGGLER:                             {objects: objs(WIGGLER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHEEP_CHEEP:                         {objects: objs(CHEEP_CHEEP)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MUNCHER:                             {objects: objs(MUNCHER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROCKY_WRENCH:                        {objects: objs(ROCKY_WRENCH)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAVA_BUBBLE:                         {objects: objs(LAVA_BUBBLE)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHAIN_CHOMP:                         {objects: objs(CHAIN_CHOMP)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_MEOWSER:                      {objects: objs(BOWSER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_STINGBY_STINGBIES:                   {objects: objs(STINGBY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHARVAARGH:                          {objects: objs(CHARVAARGH)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANT_TROOPER:                         {objects: objs(ANT_TROOPER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_JR:                           {objects: objs(BOWSER_JR)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MONTY_MOLE:                          {objects: objs(MONTY_MOLE)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FISH_BONE:                           {objects: objs(FISH_BONE)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANGRY_SUN:                           {objects: objs(ANGRY_SUN)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_CREEPER:                     {objects: objs(PIRANHA_CREEPER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MECHAKOOPA:                          {objects: objs(MECHAKOOPA)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PORCUPUFFER:                         {objects: objs(PORKUPUFFER)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLY_BULLIES:                       {objects: objs(BULLY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_THWOMP:                              {objects: objs(THWOMP)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POKEY:                               {objects: objs(POKEY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOOM_BOOM:                           {objects: objs(BOOM_BOOM)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POM_POM:                             {objects: objs(BOOM_BOOM)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LARRY:                               {objects: objs(LARRY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LEMMY:                               {objects: objs(LEMMY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MORTON:                              {objects: objs(MORTON)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WENDY:                               {objects: objs(WENDY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_IGGY:                                {objects: objs(IGGY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROY:                                 {objects: objs(ROY)},
+	REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LUDWIG:                              {objects: objs(LUDWIG)},
+
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_COIN:                                                            {objects: objs(COIN)},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_10_COIN:                                                         {objects: objs(BIG_COIN)},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_30_COIN:                                                         {objects: objs(BIG_COIN)},
+	REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_50_COIN:                                                         {objects: objs(BIG_COIN)},
+	REACH_THE_GOAL_AFTER_PICKING_UP_AT_LEAST_ALL_1_UP_MUSHROOM:                                                 {objects: objs(ONE_UP)},
+	REACH_THE_GOAL_AFTER_BREAKING_AT_LEAST_ALL_CRATES:                                                          {objects: objs(CRATE)},
+	REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH:              {objects: objs(P_SWITCH)},
+	REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_POW_BLOCK_OR_REACH_THE_GOAL_WHILE_HOLDING_A_POW_BLOCK:         {objects: objs(POW)},
+	REACH_THE_GOAL_WHILE_HOLDING_A_RED_POW_BLOCK_OR_REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_RED_POW_BLOCK: {objects: objs(POW)},
+
+	REACH_THE_GOAL_AS_SUPER_MARIO:           {objects: objs(SUPER_MUSHROOM), styles: allStyles},
+	REACH_THE_GOAL_AS_FIRE_MARIO:            {objects: objs(FIRE_FLOWER), styles: allStyles},
+	REACH_THE_GOAL_AS_BIG_MARIO:             {objects: objs(BIG_MUSHROOM), styles: styles(STYLE_M1)},
+	REACH_THE_GOAL_AS_RACCOON_MARIO:         {objects: objs(BIG_MUSHROOM), styles: styles(STYLE_M3)},
+	REACH_THE_GOAL_AS_CAPE_MARIO:            {objects: objs(BIG_MUSHROOM), styles: styles(STYLE_MW)},
+	REACH_THE_GOAL_AS_PROPELLER_MARIO:       {objects: objs(BIG_MUSHROOM), styles: styles(STYLE_WU)},
+	REACH_THE_GOAL_AS_SMB2_MARIO:            {objects: objs(SMB2_MUSHROOM), styles: styles(STYLE_M1)},
+	REACH_THE_GOAL_AS_BUILDER_MARIO:         {objects: objs(SUPER_HAMMER), styles: styles(STYLE_M1)},
+	REACH_THE_GOAL_AS_LINK:                  {styles: styles(STYLE_M1)},
+	REACH_THE_GOAL_AS_SUPERBALL_MARIO:       {styles: styles(STYLE_M1)},
+	REACH_THE_GOAL_AS_FROG_MARIO:            {styles: styles(STYLE_M3)},
+	REACH_THE_GOAL_AS_BALLOON_MARIO:         {styles: styles(STYLE_MW)},
+	REACH_THE_GOAL_AS_FLYING_SQUIRREL_MARIO: {styles: styles(STYLE_WU)},
+	REACH_THE_GOAL_AS_CAT_MARIO:             {styles: styles(STYLE_3W)},
+	REACH_THE_GOAL_AS_BOOMERANG_MARIO:       {styles: styles(STYLE_3W)},
+
+	REACH_THE_GOAL_WHILE_YOU_HAVE_SUPER_STAR_INVINCIBILITY: {objects: objs(SUPER_STAR), styles: allStyles},
+	REACH_THE_GOAL_WHILE_WEARING_A_SHOE:                    {objects: objs(SHOE_GOOMBA), styles: styles(STYLE_M1, STYLE_M3)},
+	REACH_THE_GOAL_WHILE_RIDING_YOSHI:                      {objects: objs(SHOE_GOOMBA), styles: styles(STYLE_MW, STYLE_WU)},
+	REACH_THE_GOAL_WHILE_WEARING_A_PROPELLER_BOX:           {objects: objs(PROPELLER_BOX)},
+	REACH_THE_GOAL_WHILE_WEARING_A_GOOMBA_MASK:             {objects: objs(GOOMBA_MASK)},
+	REACH_THE_GOAL_WHILE_WEARING_A_BULLET_BILL_MASK:        {objects: objs(BULLET_BILL_MASK)},
+	REACH_THE_GOAL_WHILE_WEARING_A_CANNON_BOX:              {objects: objs(CANNON_BOX)},
+	REACH_THE_GOAL_WHILE_WEARING_A_RED_POW_BOX:             {objects: objs(RED_POW_BOX)},
+	REACH_THE_GOAL_WHILE_WEARING_A_DRY_BONES_SHELL:         {objects: objs(DRY_BONES)},
+	REACH_THE_GOAL_WHILE_HOLDING_A_KOOPA_SHELL:             {objects: objs(KOOPA)},
+	REACH_THE_GOAL_WHILE_HOLDING_A_TRAMPOLINE:              {objects: objs(SPRING)},
+	REACH_THE_GOAL_ON_A_LAKITUS_CLOUD:                      {objects: objs(LAKITU_CLOUD, LAKITU)},
+	REACH_THE_GOAL_IN_A_KOOPA_CLOWN_CAR_JUNIOR_CLOWN_CAR:   {objects: objs(CLOWN_CAR)},
+	REACH_THE_GOAL_IN_A_KOOPA_TROOPA_CAR:                   {objects: objs(KOOPA_CAR), styles: styles(STYLE_3W)},
+}
+
+// Objects whose CId is something they hold or release
+var clearConContainers = objs(BLOCK, QUESTION_BLOCK, HIDDEN_BLOCK, NOTE_BLOCK, CRATE, CLOWN_CAR, PIPE, BULLET_BILL_BLASTER, CANNON, LAKITU)
+
+// Containers that release their contents again and again
+var clearConSpawnerContainers = objs(PIPE, BULLET_BILL_BLASTER, CANNON, LAKITU)
+
+type ClearConCheck struct {
+	Condition ClearConId
+	Checked   bool   // false when the condition's requirements aren't known
+	Required  uint32 // The magnitude for counted conditions, otherwise 1 if objects are needed
+	Available uint32 // Objects placed or inside containers over both areas
+	Unlimited bool   // A pipe or other spawner produces the objects
+	Shortfall uint32
+	StyleOk   bool
+}
+
+func (c *ClearConCheck) Feasible() bool {
+	return !c.Checked || (c.StyleOk && c.Shortfall == 0)
+}
+
+func (c *ClearConCheck) String() string {
+	if !c.Checked {
+		return fmt.Sprintf("%s: not checked", c.Condition)
+	}
+	if !c.StyleOk {
+		return fmt.Sprintf("%s: not possible in this game style", c.Condition)
+	}
+	if c.Shortfall != 0 {
+		return fmt.Sprintf("%s: needs %d, only %d in level", c.Condition, c.Required, c.Available)
+	}
+	return fmt.Sprintf("%s: ok", c.Condition)
+}
+
+// Whether the level has enough objects for its clear condition. Counts are an
+// upper bound, for example all big coins count for the 10, 30 and 50 coin
+// conditions and objects behind locked doors still count.
+func CheckClearCondition(level *BCD) (*ClearConCheck, error) {
+	h := &level.Header
+	c := &ClearConCheck{Condition: ClearConId(h.ClearConditionObject), StyleOk: true}
+	if c.Condition == CLEARCON_NONE {
+		c.Checked = true
+		return c, nil
+	}
+	requirement, ok := clearConRequirements[c.Condition]
+	if !ok {
+		return c, nil
+	}
+	c.Checked = true
+
+	if requirement.styles != nil {
+		c.StyleOk = false
+		for _, style := range requirement.styles {
+			if h.GameStyle == style {
+				c.StyleOk = true
+			}
+		}
+	}
+
+	if len(requirement.objects) == 0 && len(requirement.spawners) == 0 {
+		return c, nil
+	}
+	c.Required = 1
+	if clearConInfos[c.Condition].UsesMagnitude && h.ClearConditionMagnitude != 0 {
+		c.Required = uint32(h.ClearConditionMagnitude)
+	}
+
+	for areaIndex, area := range []*LevelArea{&level.OverWorld, &level.SubWorld} {
+		if int(area.ObjectCount) > len(area.Objects) {
+			return nil, fmt.Errorf("area %d object count out of range", areaIndex)
+		}
+		for _, o := range area.Objects[:area.ObjectCount] {
+			if containsObjId(requirement.spawners, ObjId(o.Id)) {
+				c.Unlimited = true
+			}
+			if containsObjId(requirement.objects, ObjId(o.Id)) {
+				c.Available++
+			}
+			if !containsObjId(clearConContainers, ObjId(o.Id)) || !clearConHoldsObject(&o) ||
+				!containsObjId(requirement.objects, ObjId(o.CId)) {
+				continue
+			}
+			if containsObjId(clearConSpawnerContainers, ObjId(o.Id)) {
+				c.Unlimited = true
+			}
+			c.Available++
+		}
+	}
+
+	if !c.Unlimited && c.Available < c.Required {
+		c.Shortfall = c.Required - c.Available
+	}
+	return c, nil
+}
+
+// Blocks, pipes and so on hold their contents in CId. A CId of 0 is both
+// empty and a Goomba, it only counts as a Goomba when CFlag is set too.
+func clearConHoldsObject(o *Object) bool {
+	return o.CId != 0xFFFF && (o.CId != 0 || o.CFlag != 0)
+}
+
+func containsObjId(ids []ObjId, id ObjId) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
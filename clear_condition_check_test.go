@@ -0,0 +1,84 @@
+package smm2_parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkTestLevel(t *testing.T, style GameStyle, id ClearConId, magnitude uint16, objects ...Object) *ClearConCheck {
+	t.Helper()
+	level := newEmptyBCD()
+	level.Header.GameStyle = style
+	// Set directly, SetClearCondition refuses conditions without a known category
+	level.Header.ClearConditionObject = uint32(id)
+	level.Header.ClearConditionMagnitude = magnitude
+	copy(level.OverWorld.Objects[:], objects)
+	level.OverWorld.ObjectCount = uint32(len(objects))
+	c, err := CheckClearCondition(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClearConEmptyContainers(t *testing.T) {
+	goombas := REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA
+	empty := Object{Id: uint16(QUESTION_BLOCK), CId: 0}
+	c := checkTestLevel(t, STYLE_M1, goombas, 1, empty, empty)
+	if c.Available != 0 || c.Feasible() {
+		t.Errorf("empty blocks counted as Goombas: %s", c)
+	}
+
+	holding := Object{Id: uint16(QUESTION_BLOCK), CId: uint16(GOOMBA), CFlag: 0x06000040}
+	c = checkTestLevel(t, STYLE_M1, goombas, 2, empty, holding, Object{Id: uint16(GOOMBA)})
+	if c.Available != 2 || !c.Feasible() {
+		t.Errorf("%d Goombas available, want 2: %s", c.Available, c)
+	}
+
+	c = checkTestLevel(t, STYLE_M1, goombas, 1, Object{Id: uint16(PIPE), CId: uint16(GOOMBA), CFlag: 1})
+	if !c.Unlimited || !c.Feasible() {
+		t.Errorf("pipe with Goombas not unlimited: %s", c)
+	}
+}
+
+func TestClearConPowerUpStyles(t *testing.T) {
+	flower := Object{Id: uint16(FIRE_FLOWER)}
+	for _, style := range allStyles {
+		c := checkTestLevel(t, style, REACH_THE_GOAL_AS_FIRE_MARIO, 0, flower)
+		if !c.Checked || !c.StyleOk || !c.Feasible() {
+			t.Errorf("fire Mario in %s: %s", style, c)
+		}
+	}
+	if c := checkTestLevel(t, STYLE_M1, REACH_THE_GOAL_AS_FIRE_MARIO, 0); c.Feasible() {
+		t.Errorf("fire Mario without a fire flower: %s", c)
+	}
+
+	for _, test := range []struct {
+		id    ClearConId
+		style GameStyle
+		ok    bool
+	}{
+		{REACH_THE_GOAL_AS_FROG_MARIO, STYLE_M3, true},
+		{REACH_THE_GOAL_AS_FROG_MARIO, STYLE_M1, false},
+		{REACH_THE_GOAL_AS_CAT_MARIO, STYLE_3W, true},
+		{REACH_THE_GOAL_AS_CAT_MARIO, STYLE_WU, false},
+		{REACH_THE_GOAL_AS_LINK, STYLE_M1, true},
+		{REACH_THE_GOAL_AS_LINK, STYLE_MW, false},
+	} {
+		c := checkTestLevel(t, test.style, test.id, 0)
+		if !c.Checked || c.StyleOk != test.ok {
+			t.Errorf("%s in %s: %s", test.id, test.style, c)
+		}
+	}
+
+	// Every power-up condition has its styles listed, the shell helmets
+	// aren't power-ups
+	for id := range clearConInfos {
+		if !strings.HasPrefix(id.String(), "REACH_THE_GOAL_AS_") || id == REACH_THE_GOAL_AS_BUZZY_MARIO || id == REACH_THE_GOAL_AS_SPINY_MARIO {
+			continue
+		}
+		if clearConRequirements[id].styles == nil {
+			t.Errorf("%s has no styles", id)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package smm2_parsing
+
+import "testing"
+
+func TestSetClearCondition(t *testing.T) {
+	for _, test := range []struct {
+		id        ClearConId
+		magnitude uint16
+		category  ClearConCategory
+		ok        bool
+	}{
+		{CLEARCON_NONE, 0, CATEGORY_NONE, true},
+		{CLEARCON_NONE, 1, 0, false},
+		{REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA, 5, CATEGORY_PARTS, true},
+		{REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA, 0, 0, false},
+		{REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH, 3, CATEGORY_PARTS, true},
+		// Categories that aren't known aren't guessed
+		{REACH_THE_GOAL_AS_FIRE_MARIO, 0, 0, false},
+		{REACH_THE_GOAL_AS_FIRE_MARIO, 1, 0, false},
+		{REACH_THE_GOAL_WHILE_HOLDING_A_KOOPA_SHELL, 0, 0, false},
+		{REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH, 0, 0, false},
+		{REACH_THE_GOAL_WHILE_RIDING_YOSHI, 0, 0, false},
+		{ClearConId(46219146), 0, 0, false},
+	} {
+		h := &Header{}
+		err := h.SetClearCondition(test.id, test.magnitude)
+		if (err == nil) != test.ok {
+			t.Errorf("SetClearCondition(%s, %d) = %v", test.id, test.magnitude, err)
+			continue
+		}
+		if err == nil && ClearConCategory(h.ClearConditionCategory) != test.category {
+			t.Errorf("SetClearCondition(%s, %d) set category %s, want %s", test.id, test.magnitude, ClearConCategory(h.ClearConditionCategory), test.category)
+		}
+		if err != nil && *h != (Header{}) {
+			t.Errorf("SetClearCondition(%s, %d) changed the header after an error", test.id, test.magnitude)
+		}
+	}
+}
+
+// Validate only checks the magnitude, categories in levels from the game may
+// not match the table
+func TestValidateClearConditionCategory(t *testing.T) {
+	h := &Header{ClearConditionObject: uint32(REACH_THE_GOAL_WHILE_HOLDING_A_TRAMPOLINE)}
+	for _, category := range []ClearConCategory{CATEGORY_STATUS, CATEGORY_ACTIONS} {
+		h.ClearConditionCategory = uint8(category)
+		if err := h.validateClearCondition(); err != nil {
+			t.Errorf("category %s: %v", category, err)
+		}
+	}
+	h.ClearConditionMagnitude = 2
+	if err := h.validateClearCondition(); err == nil {
+		t.Errorf("magnitude on a condition without one accepted")
+	}
+
+	// Unknown conditions, like the one in the parse example, are accepted
+	h = &Header{ClearConditionCategory: 2, ClearConditionObject: 46219146}
+	if err := h.validateClearCondition(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClearConInfoOf(t *testing.T) {
+	info, ok := ClearConInfoOf(REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_COIN)
+	if !ok || info.Category != CATEGORY_PARTS || !info.UsesMagnitude {
+		t.Errorf("coin condition %+v, %v", info, ok)
+	}
+	info, ok = ClearConInfoOf(REACH_THE_GOAL_WHILE_HOLDING_A_TRAMPOLINE)
+	if !ok || info.Category != CATEGORY_NONE || info.UsesMagnitude {
+		t.Errorf("trampoline condition %+v, %v", info, ok)
+	}
+	if _, ok := ClearConInfoOf(CLEARCON_NONE); ok {
+		t.Errorf("CLEARCON_NONE has info")
+	}
+}
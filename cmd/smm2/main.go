@@ -0,0 +1,377 @@
+// Command smm2 wraps smm2_parsing for shell scripts.
+//
+// Commands that convert files take an input and output path. If the input is
+// a directory every file in it is converted into the output directory under
+// the same name. Exit codes are 0 on success, 1 if any file failed and 2 for
+// invalid usage.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mm2srv/smm2_parsing"
+)
+
+const usage = `usage: smm2 <command> [arguments]
+
+commands:
+  decrypt <in> <out>             decrypt levels
+  encrypt <in> <out>             encrypt decrypted levels
+  info <path>...                 print level metadata
+  dump [-json|-text] <level>     print a level as JSON (default) or text
+  render [-subworld] [-scale n] <level> <out.png>
+  thumb encrypt <in> <out>       add the thumbnail trailer the game expects
+  thumb decrypt <in> <out>       verify and strip the thumbnail trailer
+  replay tas <replay> [out]      convert a replay to an nx-tas script
+  remove-upload-flag <in> <out>  allow uploading a level again
+  validate <path>...             check levels and thumbnails
+`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "decrypt":
+		return convertCommand(args, smm2_parsing.DecryptLevel)
+	case "encrypt":
+		return convertCommand(args, smm2_parsing.EncryptLevel)
+	case "remove-upload-flag":
+		return convertCommand(args, smm2_parsing.RemoveUploadedFlag)
+	case "info":
+		return infoCommand(args)
+	case "dump":
+		return dumpCommand(args)
+	case "render":
+		return renderCommand(args)
+	case "thumb":
+		if len(args) > 0 && args[0] == "encrypt" {
+			return convertCommand(args[1:], smm2_parsing.EncryptJpegThumbnail)
+		}
+		if len(args) > 0 && args[0] == "decrypt" {
+			return convertCommand(args[1:], smm2_parsing.DecryptJpegThumbnail)
+		}
+	case "replay":
+		if len(args) > 0 && args[0] == "tas" {
+			return replayCommand(args[1:])
+		}
+	case "validate":
+		return validateCommand(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+	return 2
+}
+
+// Convert one file, or every file in a directory into another directory
+func convertCommand(args []string, fn func([]byte) ([]byte, error)) int {
+	if len(args) != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	in, out := args[0], args[1]
+
+	stat, err := os.Stat(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !stat.IsDir() {
+		if err := convertFile(in, out, fn); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+			return 1
+		}
+		return 0
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	files, err := listFiles([]string{in})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	status := 0
+	for _, file := range files {
+		if err := convertFile(file, filepath.Join(out, filepath.Base(file)), fn); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func convertFile(in string, out string, fn func([]byte) ([]byte, error)) error {
+	buf, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	buf, err = fn(buf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, buf, 0644)
+}
+
+// Paths with directories replaced by the regular files directly inside them
+func listFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				names = append(names, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+	return files, nil
+}
+
+func infoCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	files, err := listFiles(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for i, file := range files {
+		buf, err := os.ReadFile(file)
+		if err == nil {
+			var h *smm2_parsing.Header
+			h, err = smm2_parsing.LoadHeader(buf)
+			if err == nil {
+				if i > 0 {
+					fmt.Println()
+				}
+				printHeader(file, h)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func printHeader(file string, h *smm2_parsing.Header) {
+	fmt.Printf("file: %s\n", file)
+	fmt.Printf("name: %s\n", h.CourseName())
+	fmt.Printf("description: %s\n", strings.ReplaceAll(h.CourseDescription(), "\n", " "))
+	fmt.Printf("style: %s\n", h.GameStyle)
+	fmt.Printf("time limit: %d\n", h.TimeLimit)
+	fmt.Printf("clear condition: %s %s x%d\n",
+		smm2_parsing.ClearConCategory(h.ClearConditionCategory),
+		smm2_parsing.ClearConId(h.ClearConditionObject),
+		h.ClearConditionMagnitude)
+	fmt.Printf("game version: %s\n", smm2_parsing.GameVersion(h.GameVersion))
+	fmt.Printf("created: %04d-%02d-%02d %02d:%02d\n", h.CreationYear, h.CreationMonth, h.CreationDay, h.CreationHour, h.CreationMinute)
+	if h.UploadId != 0 {
+		courseId, err := smm2_parsing.DataIdToCourseId(h.UploadId)
+		if err != nil {
+			courseId = fmt.Sprint(h.UploadId)
+		}
+		fmt.Printf("course id: %s\n", courseId)
+	}
+}
+
+func dumpCommand(args []string) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	jsonOut := flags.Bool("json", false, "print JSON, the default")
+	text := flags.Bool("text", false, "print the text format instead of JSON")
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	if *jsonOut && *text {
+		fmt.Fprintln(os.Stderr, "dump: -json and -text can't be used together")
+		return 2
+	}
+
+	level, err := loadLevel(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	var out []byte
+	if *text {
+		out, err = level.SaveText()
+	} else {
+		out, err = level.MarshalJSON()
+		out = append(out, '\n')
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
+
+// Encrypted or decrypted level depending on its size
+func loadLevel(path string) (*smm2_parsing.BCD, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	level := &smm2_parsing.BCD{}
+	if len(buf) == 0x5BFC0 {
+		err = level.LoadDecrypted(buf)
+	} else {
+		err = level.Load(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+func renderCommand(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	subworld := flags.Bool("subworld", false, "render the subworld instead of the overworld")
+	scale := flags.Int("scale", 4, "pixels per tile")
+	if flags.Parse(args) != nil || flags.NArg() != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	level, err := loadLevel(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+	area := &level.OverWorld
+	if *subworld {
+		area = &level.SubWorld
+	}
+	img, err := smm2_parsing.RenderArea(area, *scale)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		return 1
+	}
+
+	out, err := os.Create(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = png.Encode(out, img)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(1), err)
+		return 1
+	}
+	return 0
+}
+
+func replayCommand(args []string) int {
+	if len(args) != 1 && len(args) != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	in, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	replay, err := smm2_parsing.DecodeReplay(in)
+	in.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+
+	text := replay.GetTASText()
+	if len(args) == 1 {
+		fmt.Print(text)
+		return 0
+	}
+	if err := os.WriteFile(args[1], []byte(text), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func validateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	files, err := listFiles(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, file := range files {
+		err := validateFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, strings.ReplaceAll(err.Error(), "\n", "; "))
+			status = 1
+			continue
+		}
+		fmt.Printf("%s: ok\n", file)
+	}
+	return status
+}
+
+func validateFile(path string) error {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch len(buf) {
+	case 0x5c000:
+		level, err := smm2_parsing.LoadBCD(buf)
+		if err != nil {
+			return err
+		}
+		return level.Validate()
+	case 0x1c000:
+		return smm2_parsing.VerifyThumbnail(buf)
+	}
+	return fmt.Errorf("not an encrypted level or thumbnail (%d bytes)", len(buf))
+}
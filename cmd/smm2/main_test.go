@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mm2srv/smm2_parsing"
+)
+
+// Run with stdout and stderr discarded
+func quietRun(t *testing.T, args ...string) int {
+	t.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	return run(args)
+}
+
+func writeTestLevel(t *testing.T, path string, name string) *smm2_parsing.BCD {
+	t.Helper()
+	tileset := smm2_parsing.GroundTileset{}
+	for mask := 0; mask < 256; mask++ {
+		tileset[uint8(mask)] = 0
+	}
+	level, err := smm2_parsing.NewLevelBuilder().
+		Name(name).
+		Tileset(tileset).
+		Place(smm2_parsing.GOOMBA, 10, 1).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := level.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return level
+}
+
+func TestDecryptEncrypt(t *testing.T) {
+	dir := t.TempDir()
+	encrypted := filepath.Join(dir, "course_data_000.bcd")
+	decrypted := filepath.Join(dir, "decrypted.bcd")
+	reencrypted := filepath.Join(dir, "reencrypted.bcd")
+	level := writeTestLevel(t, encrypted, "Round trip")
+
+	if status := quietRun(t, "decrypt", encrypted, decrypted); status != 0 {
+		t.Fatalf("decrypt exited with %d", status)
+	}
+	buf, err := os.ReadFile(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := level.SaveDecrypted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, want) {
+		t.Fatal("decrypt output differs from the level")
+	}
+
+	if status := quietRun(t, "encrypt", decrypted, reencrypted); status != 0 {
+		t.Fatalf("encrypt exited with %d", status)
+	}
+	buf, err = os.ReadFile(reencrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := smm2_parsing.DecryptLevel(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(roundTrip, want) {
+		t.Fatal("level changed after decrypt and encrypt")
+	}
+}
+
+func TestDecryptDirectory(t *testing.T) {
+	in := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	writeTestLevel(t, filepath.Join(in, "a.bcd"), "A")
+	writeTestLevel(t, filepath.Join(in, "b.bcd"), "B")
+
+	if status := quietRun(t, "decrypt", in, out); status != 0 {
+		t.Fatalf("decrypt exited with %d", status)
+	}
+	for _, name := range []string{"a.bcd", "b.bcd"} {
+		buf, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		level := &smm2_parsing.BCD{}
+		if err := level.LoadDecrypted(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One bad file fails the run, the others are still converted
+	if err := os.WriteFile(filepath.Join(in, "c.bcd"), []byte("not a level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = filepath.Join(t.TempDir(), "out")
+	if status := quietRun(t, "decrypt", in, out); status != 1 {
+		t.Fatalf("decrypt with a bad file exited with %d", status)
+	}
+	if _, err := os.Stat(filepath.Join(out, "b.bcd")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	level := filepath.Join(dir, "level.bcd")
+	writeTestLevel(t, level, "Exit codes")
+	garbage := filepath.Join(dir, "garbage.bcd")
+	if err := os.WriteFile(garbage, []byte("not a level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+
+	for _, test := range []struct {
+		args   []string
+		status int
+	}{
+		{[]string{"info", level}, 0},
+		{[]string{"dump", level}, 0},
+		{[]string{"dump", "-text", level}, 0},
+		{[]string{"validate", level}, 0},
+		{[]string{"help"}, 0},
+
+		{[]string{"decrypt", garbage, out}, 1},
+		{[]string{"decrypt", filepath.Join(dir, "missing.bcd"), out}, 1},
+		{[]string{"info", garbage}, 1},
+		{[]string{"dump", garbage}, 1},
+		{[]string{"validate", level, garbage}, 1},
+
+		{nil, 2},
+		{[]string{"nope"}, 2},
+		{[]string{"thumb", "nope", level, out}, 2},
+		{[]string{"replay", "nope"}, 2},
+		{[]string{"decrypt", level}, 2},
+		{[]string{"info"}, 2},
+		{[]string{"dump", "-json", "-text", level}, 2},
+	} {
+		if status := quietRun(t, test.args...); status != test.status {
+			t.Errorf("%q exited with %d, want %d", test.args, status, test.status)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package smm2_parsing
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The game keeps 120 course slots, each as course_data_NNN.bcd and course_thumb_NNN.btl
+const COURSE_SLOTS = 120
+
+type CourseBundle struct {
+	Slot      int
+	Level     *BCD
+	Thumbnail []byte // Thumbnail as stored by the game, see EncryptJpegThumbnail
+
+	// Files the bundle was loaded from, empty for bundles made in code
+	LevelPath     string
+	ThumbnailPath string
+}
+
+func CourseDataName(slot int) string {
+	return fmt.Sprintf("course_data_%03d.bcd", slot)
+}
+
+func CourseThumbnailName(slot int) string {
+	return fmt.Sprintf("course_thumb_%03d.btl", slot)
+}
+
+// Slot number from a course_data_NNN.bcd or course_thumb_NNN.btl file name
+func ParseCourseSlot(name string) (slot int, isThumbnail bool, err error) {
+	var digits string
+	if rest, ok := strings.CutPrefix(name, "course_data_"); ok && strings.HasSuffix(rest, ".bcd") {
+		digits = strings.TrimSuffix(rest, ".bcd")
+	} else if rest, ok := strings.CutPrefix(name, "course_thumb_"); ok && strings.HasSuffix(rest, ".btl") {
+		digits = strings.TrimSuffix(rest, ".btl")
+		isThumbnail = true
+	}
+
+	slot, err = strconv.Atoi(digits)
+	if err != nil || len(digits) != 3 || slot < 0 || slot >= COURSE_SLOTS {
+		return 0, false, fmt.Errorf("%s is not a course file name", name)
+	}
+	return slot, isThumbnail, nil
+}
+
+func LoadCourseBundle(fsys fs.FS, slot int) (*CourseBundle, error) {
+	if slot < 0 || slot >= COURSE_SLOTS {
+		return nil, fmt.Errorf("invalid slot %d", slot)
+	}
+
+	levelBuf, err := fs.ReadFile(fsys, CourseDataName(slot))
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := fs.ReadFile(fsys, CourseThumbnailName(slot))
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := LoadBCD(levelBuf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", CourseDataName(slot), err)
+	}
+
+	b := &CourseBundle{
+		Slot:          slot,
+		Level:         level,
+		Thumbnail:     thumbnail,
+		LevelPath:     CourseDataName(slot),
+		ThumbnailPath: CourseThumbnailName(slot),
+	}
+	err = b.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Bundle from a level and thumbnail path, both file names must be for the same slot
+func LoadCourseBundleFiles(levelPath string, thumbnailPath string) (*CourseBundle, error) {
+	slot, _, err := ParseCourseSlot(filepath.Base(levelPath))
+	if err != nil {
+		return nil, err
+	}
+
+	levelBuf, err := os.ReadFile(levelPath)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := os.ReadFile(thumbnailPath)
+	if err != nil {
+		return nil, err
+	}
+	level, err := LoadBCD(levelBuf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", levelPath, err)
+	}
+
+	b := &CourseBundle{
+		Slot:          slot,
+		Level:         level,
+		Thumbnail:     thumbnail,
+		LevelPath:     levelPath,
+		ThumbnailPath: thumbnailPath,
+	}
+	err = b.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Every filled slot in fsys, a level without its thumbnail or the other way
+// around is an error
+func LoadCourseBundles(fsys fs.FS) ([]*CourseBundle, error) {
+	var bundles []*CourseBundle
+	for slot := 0; slot < COURSE_SLOTS; slot++ {
+		_, levelErr := fs.Stat(fsys, CourseDataName(slot))
+		_, thumbnailErr := fs.Stat(fsys, CourseThumbnailName(slot))
+		levelMissing := errors.Is(levelErr, fs.ErrNotExist)
+		thumbnailMissing := errors.Is(thumbnailErr, fs.ErrNotExist)
+		if levelMissing && thumbnailMissing {
+			continue
+		}
+		if levelMissing != thumbnailMissing {
+			return nil, fmt.Errorf("slot %d is missing its level or thumbnail", slot)
+		}
+
+		b, err := LoadCourseBundle(fsys, slot)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, b)
+	}
+	return bundles, nil
+}
+
+// Checks the slot, that LevelPath and ThumbnailPath when set are the level and
+// thumbnail names for that slot and that the thumbnail is encrypted with a
+// valid HMAC
+func (b *CourseBundle) Validate() error {
+	if b.Slot < 0 || b.Slot >= COURSE_SLOTS {
+		return fmt.Errorf("invalid slot %d", b.Slot)
+	}
+	if b.Level == nil {
+		return fmt.Errorf("slot %d has no level", b.Slot)
+	}
+	for _, file := range []struct {
+		path        string
+		isThumbnail bool
+	}{{b.LevelPath, false}, {b.ThumbnailPath, true}} {
+		if file.path == "" {
+			continue
+		}
+		slot, isThumbnail, err := ParseCourseSlot(filepath.Base(file.path))
+		if err != nil {
+			return err
+		}
+		if isThumbnail && !file.isThumbnail {
+			return fmt.Errorf("%s is a thumbnail, not a level", file.path)
+		}
+		if !isThumbnail && file.isThumbnail {
+			return fmt.Errorf("%s is a level, not a thumbnail", file.path)
+		}
+		if slot != b.Slot {
+			return fmt.Errorf("slot mismatch, %s is slot %d but the bundle is slot %d", file.path, slot, b.Slot)
+		}
+	}
+	err := VerifyThumbnail(b.Thumbnail)
+	if err != nil {
+		return fmt.Errorf("slot %d: %w", b.Slot, err)
+	}
+	return nil
+}
+
+// Encrypted level and the thumbnail as is, after Validate. Plain JPEGs are
+// rejected, encrypt them with EncryptJpegThumbnail first.
+func (b *CourseBundle) Save() ([]byte, []byte, error) {
+	err := b.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	levelBuf, err := b.Level.Save()
+	if err != nil {
+		return nil, nil, err
+	}
+	return levelBuf, b.Thumbnail, nil
+}
+
+func (b *CourseBundle) WriteDir(dir string) error {
+	levelBuf, thumbnail, err := b.Save()
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, CourseDataName(b.Slot)), levelBuf, 0644)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, CourseThumbnailName(b.Slot)), thumbnail, 0644)
+}
@@ -0,0 +1,76 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testJpeg(t testing.TB) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 360)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCourseBundleRoundTrip(t *testing.T) {
+	thumbnail, err := EncryptJpegThumbnail(testJpeg(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	b := &CourseBundle{Slot: 7, Level: newEmptyBCD(), Thumbnail: thumbnail}
+	if err := b.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCourseBundleFiles(filepath.Join(dir, CourseDataName(7)), filepath.Join(dir, CourseThumbnailName(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Slot != 7 || !bytes.Equal(loaded.Thumbnail, thumbnail) {
+		t.Errorf("loaded slot %d, thumbnail changed %v", loaded.Slot, !bytes.Equal(loaded.Thumbnail, thumbnail))
+	}
+	bundles, err := LoadCourseBundles(os.DirFS(dir))
+	if err != nil || len(bundles) != 1 || bundles[0].Slot != 7 {
+		t.Errorf("LoadCourseBundles = %d bundles, %v", len(bundles), err)
+	}
+}
+
+func TestCourseBundleSavePlainJpeg(t *testing.T) {
+	b := &CourseBundle{Slot: 0, Level: newEmptyBCD(), Thumbnail: testJpeg(t)}
+	if _, _, err := b.Save(); err == nil {
+		t.Errorf("plain JPEG thumbnail saved")
+	}
+}
+
+func TestCourseBundleSlotMismatch(t *testing.T) {
+	thumbnail, err := EncryptJpegThumbnail(testJpeg(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		levelPath     string
+		thumbnailPath string
+		message       string
+	}{
+		{CourseDataName(3), CourseThumbnailName(4), "slot mismatch"},
+		{CourseDataName(4), CourseThumbnailName(3), "slot mismatch"},
+		{CourseThumbnailName(3), CourseThumbnailName(3), "is a thumbnail"},
+		{CourseDataName(3), CourseDataName(3), "is a level"},
+		{"level.bcd", "", "not a course file name"},
+	} {
+		b := &CourseBundle{Slot: 3, Level: newEmptyBCD(), Thumbnail: thumbnail, LevelPath: test.levelPath, ThumbnailPath: test.thumbnailPath}
+		err := b.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("Validate(%s, %s) = %v, want an error containing %q", test.levelPath, test.thumbnailPath, err, test.message)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Course and maker IDs as shown in game (XXX-XXX-XXX) are 44 bit values written
+// in base 30, least significant digit first. From high to low bits:
+//
+//	bits 40-43: always 8
+//	bits 34-39: checksum, (data ID - 31) % 64
+//	bits 14-33: low 20 bits of the data ID after xor
+//	bit  13:    always set
+//	bit  12:    set for maker IDs
+//	bits 0-11:  high 12 bits of the data ID after xor
+
+const courseIdAlphabet = "0123456789BCDFGHJKLMNPQRSTVWXY"
+
+const courseIdXor = 0b00010110100000001110000001111100
+
+const courseIdLength = 9
+
+func encodeCourseId(dataId uint64, isMaker bool) (string, error) {
+	if dataId > 0xFFFFFFFF {
+		return "", fmt.Errorf("data id %d does not fit in 32 bits", dataId)
+	}
+
+	xored := dataId ^ courseIdXor
+	checksum := (dataId - 31) & 0x3F
+	value := uint64(8)<<40 | checksum<<34 | (xored&0xFFFFF)<<14 | 1<<13 | xored>>20
+	if isMaker {
+		value |= 1 << 12
+	}
+
+	code := make([]byte, 0, courseIdLength+2)
+	for i := 0; i < courseIdLength; i++ {
+		if i == 3 || i == 6 {
+			code = append(code, '-')
+		}
+		code = append(code, courseIdAlphabet[value%30])
+		value /= 30
+	}
+	return string(code), nil
+}
+
+func decodeCourseId(code string, isMaker bool) (uint64, error) {
+	digits := strings.ToUpper(strings.ReplaceAll(code, "-", ""))
+	if len(digits) != courseIdLength {
+		return 0, fmt.Errorf("invalid id %q, expected %d characters", code, courseIdLength)
+	}
+
+	value := uint64(0)
+	for i := courseIdLength - 1; i >= 0; i-- {
+		digit := strings.IndexByte(courseIdAlphabet, digits[i])
+		if digit < 0 {
+			return 0, fmt.Errorf("invalid id %q, %q is not allowed", code, digits[i])
+		}
+		value = value*30 + uint64(digit)
+	}
+
+	if value>>40 != 8 || value&(1<<13) == 0 {
+		return 0, fmt.Errorf("invalid id %q", code)
+	}
+	if (value&(1<<12) != 0) != isMaker {
+		if isMaker {
+			return 0, fmt.Errorf("%q is a course id, not a maker id", code)
+		}
+		return 0, fmt.Errorf("%q is a maker id, not a course id", code)
+	}
+
+	xored := (value>>14)&0xFFFFF | (value&0xFFF)<<20
+	dataId := xored ^ courseIdXor
+	if (value>>34)&0x3F != (dataId-31)&0x3F {
+		return 0, fmt.Errorf("invalid id %q, checksum mismatch", code)
+	}
+	return dataId, nil
+}
+
+// Course ID such as "XXX-XXX-XXX" from Header.UploadId or a data ID from the server
+func DataIdToCourseId(dataId uint64) (string, error) {
+	return encodeCourseId(dataId, false)
+}
+
+// Data ID from a course ID, with or without dashes
+func CourseIdToDataId(code string) (uint64, error) {
+	return decodeCourseId(code, false)
+}
+
+func PidToMakerId(pid uint64) (string, error) {
+	return encodeCourseId(pid, true)
+}
+
+func MakerIdToPid(code string) (uint64, error) {
+	return decodeCourseId(code, true)
+}
@@ -0,0 +1,108 @@
+package smm2_parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+// Pinned output of the encoder, not course IDs confirmed against the game.
+// They catch changes to the scheme, real IDs should be added as they are found.
+var courseIdTests = []struct {
+	dataId uint64
+	course string
+	maker  string
+}{
+	{31, "DQL-0V7-7DF", "X8R-0V7-7DF"},
+	{3000000, "NY6-5X0-R8G", "6JC-5X0-R8G"},
+	{3000001, "XP8-WWK-J9G", "G8F-WWK-J9G"},
+	{12345678, "WJX-Y60-QMG", "F33-070-QMG"},
+	{44581947, "6J9-MPJ-L4G", "Q2G-MPJ-L4G"},
+	{0xFFFFFFFF, "P3B-82C-28G", "7NG-82C-28G"},
+}
+
+func TestCourseIdTable(t *testing.T) {
+	for _, test := range courseIdTests {
+		course, err := DataIdToCourseId(test.dataId)
+		if err != nil || course != test.course {
+			t.Errorf("DataIdToCourseId(%d) = %s, %v, want %s", test.dataId, course, err, test.course)
+		}
+		maker, err := PidToMakerId(test.dataId)
+		if err != nil || maker != test.maker {
+			t.Errorf("PidToMakerId(%d) = %s, %v, want %s", test.dataId, maker, err, test.maker)
+		}
+
+		for _, code := range []string{test.course, strings.ReplaceAll(test.course, "-", ""), strings.ToLower(test.course)} {
+			dataId, err := CourseIdToDataId(code)
+			if err != nil || dataId != test.dataId {
+				t.Errorf("CourseIdToDataId(%s) = %d, %v, want %d", code, dataId, err, test.dataId)
+			}
+		}
+		pid, err := MakerIdToPid(test.maker)
+		if err != nil || pid != test.dataId {
+			t.Errorf("MakerIdToPid(%s) = %d, %v, want %d", test.maker, pid, err, test.dataId)
+		}
+	}
+}
+
+func TestCourseIdRoundTrip(t *testing.T) {
+	// Every data ID below 2^20 and then steps over the rest of the 32 bits
+	for dataId := uint64(0); dataId <= 0xFFFFFFFF; {
+		for _, isMaker := range []bool{false, true} {
+			code, err := encodeCourseId(dataId, isMaker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeCourseId(code, isMaker)
+			if err != nil || decoded != dataId {
+				t.Fatalf("%d encoded as %s decoded as %d, %v", dataId, code, decoded, err)
+			}
+		}
+		if dataId < 1<<20 {
+			dataId++
+		} else {
+			dataId += 65521
+		}
+	}
+}
+
+// Code for a raw 44 bit value, for building invalid IDs
+func rawCourseId(value uint64) string {
+	code := ""
+	for i := 0; i < courseIdLength; i++ {
+		code += string(courseIdAlphabet[value%30])
+		value /= 30
+	}
+	return code
+}
+
+func TestCourseIdInvalid(t *testing.T) {
+	const dataId uint64 = 3000000
+	xored := dataId ^ courseIdXor
+	badChecksum := (dataId - 31 + 1) & 0x3F
+	value := uint64(8)<<40 | badChecksum<<34 | (xored&0xFFFFF)<<14 | 1<<13 | xored>>20
+
+	for _, test := range []struct {
+		code    string
+		message string
+	}{
+		{rawCourseId(value), "checksum"},
+		{"NY6-5X0-R8A", "not allowed"},
+		{"NY6-5X0-R8O", "not allowed"},
+		{"NY6-5X0-R8", "expected 9 characters"},
+		{"NY6-5X0-R8GG", "expected 9 characters"},
+		{"", "expected 9 characters"},
+		{"6JC-5X0-R8G", "maker id"},
+	} {
+		_, err := CourseIdToDataId(test.code)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("CourseIdToDataId(%q) = %v, want an error containing %q", test.code, err, test.message)
+		}
+	}
+
+	if _, err := MakerIdToPid("NY6-5X0-R8G"); err == nil {
+		t.Errorf("course id accepted as a maker id")
+	}
+	if _, err := DataIdToCourseId(1 << 32); err == nil {
+		t.Errorf("data id over 32 bits accepted")
+	}
+}
@@ -2,7 +2,9 @@ package smm2_parsing
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/binary"
+	"io"
 )
 
 type Random struct {
@@ -39,6 +41,51 @@ func createKey(r *Random, table []uint32, size uint32, writer *bytes.Buffer) {
 			b := (table[index] >> shift) & 0xFF
 			value = (value << 8) | b
 		}
-		binary.Write(writer, binary.LittleEndian, uint32(value))
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], value)
+		writer.Write(b[:])
 	}
 }
+
+func newRandomFromSeed(seed []byte) *Random {
+	return &Random{
+		binary.LittleEndian.Uint32(seed[0:4]),
+		binary.LittleEndian.Uint32(seed[4:8]),
+		binary.LittleEndian.Uint32(seed[8:12]),
+		binary.LittleEndian.Uint32(seed[12:16]),
+	}
+}
+
+// Where the random seeds and IVs written by EncryptLevel and
+// EncryptJpegThumbnail come from
+type EncryptOptions struct {
+	Rand io.Reader // Defaults to crypto/rand
+}
+
+// Seeds and IVs of {1, 2, ..., 16} like older versions of this library, for
+// reproducible output
+func DeterministicEncryption() *EncryptOptions {
+	return &EncryptOptions{Rand: deterministicReader{}}
+}
+
+type deterministicReader struct{}
+
+func (deterministicReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(i%16 + 1)
+	}
+	return len(p), nil
+}
+
+func (o *EncryptOptions) randomBytes(size int) ([]byte, error) {
+	reader := rand.Reader
+	if o != nil && o.Rand != nil {
+		reader = o.Rand
+	}
+	buf := make([]byte, size)
+	_, err := io.ReadFull(reader, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,59 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+// SHA-256 of EncryptLevel output from before the seed and IV were random,
+// when both were always {1, 2, ..., 16}
+const fixedSeedLevelSHA256 = "8f62638df993140fbf3902cd57d66854f679efe0fafe1735249f86300b0b7300"
+
+func TestDeterministicEncryption(t *testing.T) {
+	decrypted := make([]byte, decryptedLevelSize)
+	rand.New(rand.NewSource(1)).Read(decrypted)
+
+	for i := 0; i < 2; i++ {
+		encrypted, err := EncryptLevelWithOptions(decrypted, DeterministicEncryption())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(encrypted)
+		if hex.EncodeToString(sum[:]) != fixedSeedLevelSHA256 {
+			t.Fatalf("call %d: got %x, want the fixed seed output %s", i, sum, fixedSeedLevelSHA256)
+		}
+	}
+
+	if DeterministicEncryption() == DeterministicEncryption() {
+		t.Fatal("DeterministicEncryption returned shared options")
+	}
+}
+
+func TestRandomEncryption(t *testing.T) {
+	decrypted := make([]byte, decryptedLevelSize)
+	rand.New(rand.NewSource(1)).Read(decrypted)
+
+	a, err := EncryptLevel(decrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncryptLevelWithOptions(decrypted, &EncryptOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) || bytes.Equal(a[0x5BFD0:0x5BFF0], b[0x5BFD0:0x5BFF0]) {
+		t.Fatal("two encryptions used the same seed and IV")
+	}
+	for _, encrypted := range [][]byte{a, b} {
+		got, err := DecryptLevel(encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, decrypted) {
+			t.Fatal("level changed after encrypting and decrypting")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build ignore
+
+// Writes level_format_enum_names.go with a name table and the String,
+// MarshalText, UnmarshalText and Parse functions for every integer type
+// declared in level_format_enums.go. Run with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+type enumConst struct {
+	name  string
+	value uint64
+}
+
+func main() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "level_format_enums.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Integer types in declaration order
+	var types []string
+	consts := map[string][]enumConst{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if ident, ok := spec.Type.(*ast.Ident); ok && strings.HasPrefix(ident.Name, "uint") {
+					types = append(types, spec.Name.Name)
+				}
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST || spec.Type == nil {
+					continue
+				}
+				typeName := spec.Type.(*ast.Ident).Name
+				for i, name := range spec.Names {
+					lit, ok := spec.Values[i].(*ast.BasicLit)
+					if !ok {
+						log.Fatalf("%s: only literal values are supported", name.Name)
+					}
+					value, ok := constant.Uint64Val(constant.MakeFromLiteral(lit.Value, lit.Kind, 0))
+					if !ok {
+						log.Fatalf("%s: invalid value %s", name.Name, lit.Value)
+					}
+					consts[typeName] = append(consts[typeName], enumConst{name.Name, value})
+				}
+			}
+		}
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprint(out, "// Code generated by gen_enum_names.go from level_format_enums.go. DO NOT EDIT.\n\n")
+	fmt.Fprint(out, "package smm2_parsing\n")
+	for _, typeName := range types {
+		values := consts[typeName]
+		if len(values) == 0 {
+			continue
+		}
+		sort.SliceStable(values, func(i, j int) bool { return values[i].value < values[j].value })
+		mapName := strings.ToLower(typeName[:1]) + typeName[1:] + "Names"
+
+		fmt.Fprintf(out, "\nvar %s = map[%s]string{\n", mapName, typeName)
+		for i, c := range values {
+			// The first name wins for values with several names
+			if i > 0 && values[i-1].value == c.value {
+				continue
+			}
+			fmt.Fprintf(out, "\t%d: %q,\n", c.value, c.name)
+		}
+		fmt.Fprint(out, "}\n")
+		fmt.Fprintf(out, `
+func (v %[1]s) String() string {
+	return enumString(%[2]s, v)
+}
+
+func (v %[1]s) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *%[1]s) UnmarshalText(text []byte) error {
+	parsed, err := Parse%[1]s(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func Parse%[1]s(s string) (%[1]s, error) {
+	return enumParse(%[2]s, s)
+}
+`, typeName, mapName)
+	}
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.WriteFile("level_format_enum_names.go", formatted, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
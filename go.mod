@@ -3,6 +3,6 @@ module github.com/mm2srv/smm2_parsing
 go 1.20
 
 require (
-	github.com/aead/cmac v0.0.0-20160719120800-7af84192f0b1 // indirect
-	honnef.co/go/spew v0.0.0-20160306144918-6a474d848f64 // indirect
+	github.com/aead/cmac v0.0.0-20160719120800-7af84192f0b1
+	honnef.co/go/spew v0.0.0-20160306144918-6a474d848f64
 )
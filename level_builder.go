@@ -0,0 +1,317 @@
+package smm2_parsing
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Ground the builder starts each course with, in tiles
+var (
+	builderStartGround = image.Rect(0, 0, 7, 1)
+	builderGoalGround  = image.Rect(226, 0, 240, 1)
+)
+
+const builderGoalX = 232
+
+// Builds a course step by step, positions are in tiles with 0,0 the bottom
+// left tile of the area. Every step is checked and problems are collected and
+// returned together by Build, so calls can be chained.
+type LevelBuilder struct {
+	level   *BCD
+	area    *LevelArea
+	grids   [2]*GroundGrid
+	tileset GroundTileset
+	errs    []error
+}
+
+// Options for Place and Pipe, errors are reported by Build
+type ObjectOption func(o *Object) error
+
+// Size in tiles from 1 to 255, objects are 1x1 by default
+func WithSize(width int, height int) ObjectOption {
+	return func(o *Object) error {
+		if width < 1 || height < 1 || width > 0xFF || height > 0xFF {
+			return fmt.Errorf("invalid size %dx%d", width, height)
+		}
+		o.Width = uint8(width)
+		o.Height = uint8(height)
+		return nil
+	}
+}
+
+// Object.Flag bits to set, like OBJFLAG_WINGS
+func WithFlags(flags uint32) ObjectOption {
+	return func(o *Object) error {
+		o.Flag |= flags
+		return nil
+	}
+}
+
+// Object inside a block, pipe or other container
+func WithContents(id ObjId) ObjectOption {
+	return func(o *Object) error {
+		o.CId = uint16(id)
+		return nil
+	}
+}
+
+// Link to a track or path with the same LId
+func WithLink(lid uint16) ObjectOption {
+	return func(o *Object) error {
+		o.LId = lid
+		return nil
+	}
+}
+
+// A course with the defaults of newEmptyBCD, the start and goal standing on
+// ground and the overworld selected
+func NewLevelBuilder() *LevelBuilder {
+	b := &LevelBuilder{level: newEmptyBCD()}
+	b.level.Header.YStart = uint8(builderStartGround.Max.Y)
+	b.level.Header.YGoal = uint8(builderGoalGround.Max.Y)
+	b.level.Header.XGoal = builderGoalX * 10
+	b.area = &b.level.OverWorld
+	b.Ground(builderStartGround)
+	b.Ground(builderGoalGround)
+	return b
+}
+
+func (b *LevelBuilder) fail(format string, args ...any) *LevelBuilder {
+	b.errs = append(b.errs, fmt.Errorf("%s: %s", b.areaName(), fmt.Sprintf(format, args...)))
+	return b
+}
+
+func (b *LevelBuilder) areaName() string {
+	if b.area == &b.level.SubWorld {
+		return "subworld"
+	}
+	return "overworld"
+}
+
+func (b *LevelBuilder) areaIndex() int {
+	if b.area == &b.level.SubWorld {
+		return 1
+	}
+	return 0
+}
+
+// Area size in tiles
+func (b *LevelBuilder) bounds() image.Rectangle {
+	return image.Rect(0, 0, int(b.area.BoundaryRight)/16, int(b.area.BoundaryTop)/16)
+}
+
+func (b *LevelBuilder) Name(name string) *LevelBuilder {
+	if err := encodeUCS2Field(b.level.Header.Name[:], name); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("name: %v", err))
+	}
+	return b
+}
+
+func (b *LevelBuilder) Description(description string) *LevelBuilder {
+	if err := encodeUCS2Field(b.level.Header.Description[:], description); err != nil {
+		b.errs = append(b.errs, fmt.Errorf("description: %v", err))
+	}
+	return b
+}
+
+func (b *LevelBuilder) Style(style GameStyle) *LevelBuilder {
+	b.level.Header.GameStyle = style
+	return b
+}
+
+func (b *LevelBuilder) TimeLimit(seconds uint16) *LevelBuilder {
+	b.level.Header.TimeLimit = seconds
+	return b
+}
+
+func (b *LevelBuilder) ClearCondition(id ClearConId, magnitude uint16) *LevelBuilder {
+	if err := b.level.Header.SetClearCondition(id, magnitude); err != nil {
+		b.errs = append(b.errs, err)
+	}
+	return b
+}
+
+// Ground tiles are given their Id from the tileset by Build. There is no
+// built-in tileset since the game's Ids aren't known, so Build fails without
+// one, see LearnGroundTileset.
+func (b *LevelBuilder) Tileset(tileset GroundTileset) *LevelBuilder {
+	b.tileset = tileset
+	return b
+}
+
+// Switch to the overworld for the following steps
+func (b *LevelBuilder) OverWorld() *LevelBuilder {
+	b.area = &b.level.OverWorld
+	return b
+}
+
+// Switch to the subworld for the following steps
+func (b *LevelBuilder) SubWorld() *LevelBuilder {
+	b.area = &b.level.SubWorld
+	return b
+}
+
+// Theme of the current area
+func (b *LevelBuilder) Theme(theme CourseTheme) *LevelBuilder {
+	if _, ok := courseThemeNames[theme]; !ok {
+		return b.fail("unknown theme %d", theme)
+	}
+	b.area.Theme = uint8(theme)
+	return b
+}
+
+// Size of the current area in tiles. Nothing is moved when an area is made
+// smaller, Build reports what ends up outside it.
+func (b *LevelBuilder) AreaSize(width int, height int) *LevelBuilder {
+	if width < 1 || height < 1 || width > 256 || height > 256 {
+		return b.fail("invalid area size %dx%d", width, height)
+	}
+	b.area.BoundaryRight = uint32(width * 16)
+	b.area.BoundaryTop = uint32(height * 16)
+	return b
+}
+
+// Place an object with its bottom left tile at x, y
+func (b *LevelBuilder) Place(id ObjId, x int, y int, opts ...ObjectOption) *LevelBuilder {
+	if _, ok := objIdNames[id]; !ok {
+		return b.fail("unknown object %d", id)
+	}
+	if int(b.area.ObjectCount) >= len(b.area.Objects) {
+		return b.fail("more than %d objects", len(b.area.Objects))
+	}
+
+	o := Object{Id: uint16(id), Width: 1, Height: 1}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return b.fail("%s at %d,%d: %v", id, x, y, err)
+		}
+	}
+	if o.Width == 0 || o.Height == 0 {
+		return b.fail("%s at %d,%d has size %dx%d", id, x, y, o.Width, o.Height)
+	}
+	r := image.Rect(x, y, x+int(o.Width), y+int(o.Height))
+	if !r.In(b.bounds()) {
+		return b.fail("%s at %d,%d is outside the area", id, x, y)
+	}
+	// Positions are the center of the object
+	o.X = uint32(x*OBJECT_TILE_UNITS + int(o.Width)*OBJECT_TILE_UNITS/2)
+	o.Y = uint32(y*OBJECT_TILE_UNITS + int(o.Height)*OBJECT_TILE_UNITS/2)
+	b.area.Objects[b.area.ObjectCount] = o
+	b.area.ObjectCount++
+	return b
+}
+
+// Fill a rectangle of tiles with ground
+func (b *LevelBuilder) Ground(r image.Rectangle) *LevelBuilder {
+	if r.Empty() || !r.In(b.bounds()) {
+		return b.fail("ground %v is outside the area", r)
+	}
+	grid := b.grids[b.areaIndex()]
+	if grid == nil {
+		// Large enough for any area size
+		grid = NewGroundGrid(256, 256)
+		b.grids[b.areaIndex()] = grid
+	}
+	grid.SetRect(r, true)
+	return b
+}
+
+// Add a track piece at x, y, its type must have a shape in TrackShapeOf
+func (b *LevelBuilder) Track(trackType uint8, x int, y int, lid uint16) *LevelBuilder {
+	if _, ok := TrackShapeOf(trackType); !ok {
+		return b.fail("unknown track type %d", trackType)
+	}
+	if !image.Pt(x, y).In(b.bounds()) {
+		return b.fail("track at %d,%d is outside the area", x, y)
+	}
+	if int(b.area.TrackCount) >= len(b.area.Tracks) {
+		return b.fail("more than %d tracks", len(b.area.Tracks))
+	}
+	b.area.Tracks[b.area.TrackCount] = Track{X: uint8(x), Y: uint8(y), Type: trackType, LId: lid}
+	b.area.TrackCount++
+	return b
+}
+
+// Place a pipe 2 tiles wide and height tiles tall with its bottom left tile at
+// x, y. Use WithContents for what comes out of it.
+func (b *LevelBuilder) Pipe(x int, y int, height int, opts ...ObjectOption) *LevelBuilder {
+	if height < 1 || height > 0xFF {
+		return b.fail("invalid pipe height %d", height)
+	}
+	return b.Place(PIPE, x, y, append([]ObjectOption{WithSize(2, height)}, opts...)...)
+}
+
+// The course with its ground tiled, or every problem found while building it,
+// anything outside its area after AreaSize and the problems Validate finds
+func (b *LevelBuilder) Build() (*BCD, error) {
+	errs := append([]error{}, b.errs...)
+	if b.tileset == nil {
+		errs = append(errs, fmt.Errorf("no tileset for the ground"))
+	}
+	errs = append(errs, b.checkAreas()...)
+	for i, area := range []*LevelArea{&b.level.OverWorld, &b.level.SubWorld} {
+		if b.grids[i] == nil {
+			continue
+		}
+		if err := area.SetGround(b.grids[i], b.tileset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		if err := b.level.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	level := *b.level
+	return &level, nil
+}
+
+// Start, goal, ground, objects and tracks placed before an area was made
+// smaller can end up outside it
+func (b *LevelBuilder) checkAreas() []error {
+	var errs []error
+	h := &b.level.Header
+	for i, area := range []*LevelArea{&b.level.OverWorld, &b.level.SubWorld} {
+		name := "overworld"
+		if i == 1 {
+			name = "subworld"
+		}
+		bounds := image.Rect(0, 0, int(area.BoundaryRight)/16, int(area.BoundaryTop)/16)
+		if i == 0 {
+			if goal := image.Pt(int(h.XGoal)/10, int(h.YGoal)); !goal.In(bounds) {
+				errs = append(errs, fmt.Errorf("%s: goal at %d,%d is outside the area", name, goal.X, goal.Y))
+			}
+			if int(h.YStart) >= bounds.Max.Y {
+				errs = append(errs, fmt.Errorf("%s: start height %d is outside the area", name, h.YStart))
+			}
+		}
+
+		if b.grids[i] != nil {
+			outside := 0
+			for _, c := range b.grids[i].Cells() {
+				if !c.In(bounds) {
+					outside++
+				}
+			}
+			if outside != 0 {
+				errs = append(errs, fmt.Errorf("%s: %d ground cells are outside the area", name, outside))
+			}
+		}
+		for _, o := range area.Objects[:area.ObjectCount] {
+			t := objectTile(&o)
+			if !image.Rect(t.X, t.Y, t.X+int(o.Width), t.Y+int(o.Height)).In(bounds) {
+				errs = append(errs, fmt.Errorf("%s: %s at %d,%d is outside the area", name, ObjId(o.Id), t.X, t.Y))
+			}
+		}
+		for _, t := range area.Tracks[:area.TrackCount] {
+			if !image.Pt(int(t.X), int(t.Y)).In(bounds) {
+				errs = append(errs, fmt.Errorf("%s: track at %d,%d is outside the area", name, t.X, t.Y))
+			}
+		}
+	}
+	return errs
+}
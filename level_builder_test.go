@@ -0,0 +1,102 @@
+package smm2_parsing
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+// Tileset with the neighbour mask as the Id, covering every mask
+func testTileset() GroundTileset {
+	tileset := GroundTileset{}
+	for mask := 0; mask < 256; mask++ {
+		tileset[uint8(mask)] = uint8(mask)
+	}
+	return tileset
+}
+
+func TestLevelBuilder(t *testing.T) {
+	level, err := NewLevelBuilder().
+		Name("Builder test").
+		Tileset(testTileset()).
+		ClearCondition(REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA, 2).
+		Place(GOOMBA, 10, 1).
+		Pipe(20, 0, 3, WithContents(GOOMBA)).
+		Ground(image.Rect(7, 0, 30, 1)).
+		Place(LIFT, 40, 5, WithSize(3, 1), WithLink(1)).
+		Track(0, 40, 5, 1).
+		SubWorld().
+		Theme(UNDERGROUND).
+		Ground(image.Rect(0, 0, 10, 2)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &level.Header
+	if h.XGoal != builderGoalX*10 || h.YGoal != 1 || h.YStart != 1 {
+		t.Errorf("goal %d,%d start %d", h.XGoal, h.YGoal, h.YStart)
+	}
+	o := level.OverWorld.Objects[1]
+	if level.OverWorld.ObjectCount != 3 || ObjId(o.Id) != PIPE || o.Width != 2 || o.Height != 3 || ObjId(o.CId) != GOOMBA {
+		t.Fatalf("pipe %+v", o)
+	}
+	if o.X != 21*OBJECT_TILE_UNITS || o.Y != 3*OBJECT_TILE_UNITS/2 {
+		t.Errorf("pipe at %d,%d", o.X, o.Y)
+	}
+	if level.SubWorld.GroundCount != 20 || level.OverWorld.TrackCount != 1 {
+		t.Errorf("%d subworld ground cells, %d tracks", level.SubWorld.GroundCount, level.OverWorld.TrackCount)
+	}
+	grid, err := level.OverWorld.GroundGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range level.OverWorld.Ground[:level.OverWorld.GroundCount] {
+		if g.Id != grid.Neighbours(int(g.X), int(g.Y)) {
+			t.Fatalf("ground at %d,%d has Id %d", g.X, g.Y, g.Id)
+		}
+	}
+}
+
+func TestLevelBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		b        *LevelBuilder
+		messages []string
+	}{
+		{"no tileset", NewLevelBuilder(), []string{"no tileset"}},
+		{"narrower", NewLevelBuilder().Tileset(testTileset()).AreaSize(100, 27), []string{
+			"overworld: goal at 232,1 is outside the area",
+			"overworld: 14 ground cells are outside the area",
+		}},
+		{"lower", NewLevelBuilder().Tileset(testTileset()).Place(GOOMBA, 10, 20).Track(0, 12, 20, 0).AreaSize(240, 15), []string{
+			"overworld: GOOMBA at 10,20 is outside the area",
+			"overworld: track at 12,20 is outside the area",
+		}},
+		{"start", NewLevelBuilder().Tileset(testTileset()).Ground(image.Rect(0, 1, 7, 6)).AreaSize(240, 1), []string{
+			"start height 1 is outside the area",
+		}},
+		{"subworld", NewLevelBuilder().Tileset(testTileset()).SubWorld().Ground(image.Rect(0, 20, 5, 21)).AreaSize(240, 10), []string{
+			"subworld: 5 ground cells are outside the area",
+		}},
+		{"size", NewLevelBuilder().Tileset(testTileset()).Place(BLOCK, 0, 5, WithSize(300, 1)).Place(BLOCK, 0, 5, WithSize(0, 1)), []string{
+			"BLOCK at 0,5: invalid size 300x1",
+			"BLOCK at 0,5: invalid size 0x1",
+		}},
+		{"outside", NewLevelBuilder().Tileset(testTileset()).Place(BLOCK, 239, 5, WithSize(2, 1)).Track(200, 5, 5, 0), []string{
+			"BLOCK at 239,5 is outside the area",
+			"unknown track type 200",
+		}},
+	} {
+		level, err := test.b.Build()
+		if err == nil || level != nil {
+			t.Errorf("%s: built without an error", test.name)
+			continue
+		}
+		for _, message := range test.messages {
+			if !strings.Contains(err.Error(), message) {
+				t.Errorf("%s: %v, want an error containing %q", test.name, err, message)
+			}
+		}
+	}
+}
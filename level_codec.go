@@ -0,0 +1,464 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// Hand written little endian codec for BCD, produces the same bytes as
+// binary.Read/binary.Write without reflection. Offsets are from the structs in
+// level_format.go.
+
+const (
+	headerSize           = 0x200
+	levelAreaSize        = 0x2DEE0
+	decryptedLevelSize   = headerSize + 2*levelAreaSize // 0x5BFC0
+	objectSize           = 32
+	soundSize            = 4
+	snakeSize            = 964
+	clearPipeSize        = 292
+	piranhaCreeperSize   = 84
+	exclamationBlockSize = 44
+	trackBlockSize       = 44
+	groundSize           = 4
+	trackSize            = 12
+	icicleSize           = 4
+)
+
+// Decrypted levels are large, Load reuses them between calls
+var levelBufferPool = sync.Pool{
+	New: func() any {
+		return new([decryptedLevelSize]byte)
+	},
+}
+
+func (s *BCD) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, decryptedLevelSize)
+	encodeBCD(buf, s)
+	return buf, nil
+}
+
+func (s *BCD) UnmarshalBinary(buf []byte) error {
+	if len(buf) != decryptedLevelSize {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), decryptedLevelSize)
+	}
+	decodeBCD(s, buf)
+	return nil
+}
+
+// Reusable level decoder, keeps its buffers between calls so decoding many
+// levels into the same BCD doesn't allocate. Not safe for concurrent use,
+// use one Decoder per goroutine.
+type Decoder struct {
+	buf    [decryptedLevelSize]byte
+	keyBuf bytes.Buffer
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Decrypt and decode an encrypted level into s, overwriting all of it
+func (d *Decoder) Decode(buf []byte, s *BCD) error {
+	if len(buf) != 0x5c000 {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
+	}
+	err := decryptLevelInto(d.buf[:], buf, &d.keyBuf)
+	if err != nil {
+		return err
+	}
+	decodeBCD(s, d.buf[:])
+	return nil
+}
+
+// Decode only the header of an encrypted level, see LoadHeader
+func (d *Decoder) DecodeHeader(buf []byte, h *Header) error {
+	if len(buf) != 0x5c000 {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
+	}
+	decryptLevelHeaderInto(d.buf[:headerSize], buf, &d.keyBuf)
+	decodeHeader(h, d.buf[:headerSize])
+	return nil
+}
+
+// Decrypt only the first 0x200 bytes (the Header) of an encrypted level. The
+// CRC and CMAC cover the whole level so they aren't checked, use VerifyLevel
+// for that.
+func DecryptLevelHeader(buf []byte) ([]byte, error) {
+	if len(buf) != 0x5c000 {
+		return []byte{}, fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
+	}
+	decrypted := make([]byte, headerSize)
+	decryptLevelHeaderInto(decrypted, buf, &bytes.Buffer{})
+	return decrypted, nil
+}
+
+func LoadHeader(buf []byte) (*Header, error) {
+	decrypted, err := DecryptLevelHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	h := &Header{}
+	decodeHeader(h, decrypted)
+	return h, nil
+}
+
+// Check the CRC and CMAC of an encrypted level without decoding it
+func VerifyLevel(buf []byte) error {
+	if len(buf) != 0x5c000 {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
+	}
+	decrypted := levelBufferPool.Get().(*[decryptedLevelSize]byte)
+	defer levelBufferPool.Put(decrypted)
+	return decryptLevelInto(decrypted[:], buf, &bytes.Buffer{})
+}
+
+func decryptLevelHeaderInto(dst []byte, buf []byte, keyBuf *bytes.Buffer) {
+	end := 0x5BFD0
+	r := newRandomFromSeed(buf[end+0x10 : end+0x20])
+
+	keyBuf.Reset()
+	createKey(r, bcdTable, 0x10, keyBuf)
+	// The key is always 16 bytes
+	aesBlock, _ := aes.NewCipher(keyBuf.Bytes())
+
+	// CBC only needs the previous ciphertext block, so the header can be
+	// decrypted on its own
+	aesMode := cipher.NewCBCDecrypter(aesBlock, buf[end:end+0x10])
+	aesMode.CryptBlocks(dst, buf[0x10:0x10+headerSize])
+}
+
+func encodeBCD(buf []byte, s *BCD) {
+	encodeHeader(buf[:headerSize], &s.Header)
+	encodeLevelArea(buf[headerSize:headerSize+levelAreaSize], &s.OverWorld)
+	encodeLevelArea(buf[headerSize+levelAreaSize:], &s.SubWorld)
+}
+
+func decodeBCD(s *BCD, buf []byte) {
+	decodeHeader(&s.Header, buf[:headerSize])
+	decodeLevelArea(&s.OverWorld, buf[headerSize:headerSize+levelAreaSize])
+	decodeLevelArea(&s.SubWorld, buf[headerSize+levelAreaSize:])
+}
+
+func encodeHeader(b []byte, h *Header) {
+	b[0] = h.YStart
+	b[1] = h.YGoal
+	binary.LittleEndian.PutUint16(b[2:], h.XGoal)
+	binary.LittleEndian.PutUint16(b[4:], h.TimeLimit)
+	binary.LittleEndian.PutUint16(b[6:], h.ClearConditionMagnitude)
+	binary.LittleEndian.PutUint16(b[8:], h.CreationYear)
+	b[10] = h.CreationMonth
+	b[11] = h.CreationDay
+	b[12] = h.CreationHour
+	b[13] = h.CreationMinute
+	b[14] = h.AutoscrollSpeed
+	b[15] = h.ClearConditionCategory
+	binary.LittleEndian.PutUint32(b[16:], h.ClearConditionObject)
+	binary.LittleEndian.PutUint32(b[20:], h.UnkGameVer)
+	binary.LittleEndian.PutUint32(b[24:], h.ManagementFlags)
+	binary.LittleEndian.PutUint32(b[28:], h.ClearAttemmpts)
+	binary.LittleEndian.PutUint32(b[32:], h.ClearCheckTime)
+	binary.LittleEndian.PutUint32(b[36:], h.CreationId)
+	binary.LittleEndian.PutUint64(b[40:], h.UploadId)
+	binary.LittleEndian.PutUint32(b[48:], h.GameVersion)
+	copy(b[52:241], h.Unk1[:])
+	copy(b[241:243], h.GameStyle[:])
+	b[243] = h.Unk2
+	copy(b[244:310], h.Name[:])
+	copy(b[310:512], h.Description[:])
+}
+
+func decodeHeader(h *Header, b []byte) {
+	h.YStart = b[0]
+	h.YGoal = b[1]
+	h.XGoal = binary.LittleEndian.Uint16(b[2:])
+	h.TimeLimit = binary.LittleEndian.Uint16(b[4:])
+	h.ClearConditionMagnitude = binary.LittleEndian.Uint16(b[6:])
+	h.CreationYear = binary.LittleEndian.Uint16(b[8:])
+	h.CreationMonth = b[10]
+	h.CreationDay = b[11]
+	h.CreationHour = b[12]
+	h.CreationMinute = b[13]
+	h.AutoscrollSpeed = b[14]
+	h.ClearConditionCategory = b[15]
+	h.ClearConditionObject = binary.LittleEndian.Uint32(b[16:])
+	h.UnkGameVer = binary.LittleEndian.Uint32(b[20:])
+	h.ManagementFlags = binary.LittleEndian.Uint32(b[24:])
+	h.ClearAttemmpts = binary.LittleEndian.Uint32(b[28:])
+	h.ClearCheckTime = binary.LittleEndian.Uint32(b[32:])
+	h.CreationId = binary.LittleEndian.Uint32(b[36:])
+	h.UploadId = binary.LittleEndian.Uint64(b[40:])
+	h.GameVersion = binary.LittleEndian.Uint32(b[48:])
+	copy(h.Unk1[:], b[52:241])
+	copy(h.GameStyle[:], b[241:243])
+	h.Unk2 = b[243]
+	copy(h.Name[:], b[244:310])
+	copy(h.Description[:], b[310:512])
+}
+
+func encodeLevelArea(b []byte, a *LevelArea) {
+	b[0] = a.Theme
+	b[1] = a.AutoscrollType
+	b[2] = a.BoundaryType
+	b[3] = a.Orientation
+	b[4] = a.LiquidEndHeight
+	b[5] = a.LiquidType
+	b[6] = a.LiquidSpeed
+	b[7] = a.LiquidStartHeight
+	binary.LittleEndian.PutUint32(b[8:], a.BoundaryRight)
+	binary.LittleEndian.PutUint32(b[12:], a.BoundaryTop)
+	binary.LittleEndian.PutUint32(b[16:], a.BoundaryLeft)
+	binary.LittleEndian.PutUint32(b[20:], a.BoundaryBottom)
+	binary.LittleEndian.PutUint32(b[24:], a.UnkFlag)
+	binary.LittleEndian.PutUint32(b[28:], a.ObjectCount)
+	binary.LittleEndian.PutUint32(b[32:], a.SoundEffectCount)
+	binary.LittleEndian.PutUint32(b[36:], a.SnakeBlockCount)
+	binary.LittleEndian.PutUint32(b[40:], a.ClearPipeCount)
+	binary.LittleEndian.PutUint32(b[44:], a.PiranhaCreeperCount)
+	binary.LittleEndian.PutUint32(b[48:], a.ExclamationMarkBlockCount)
+	binary.LittleEndian.PutUint32(b[52:], a.TrackBlockCount)
+	binary.LittleEndian.PutUint32(b[56:], a.Unk1)
+	binary.LittleEndian.PutUint32(b[60:], a.GroundCount)
+	binary.LittleEndian.PutUint32(b[64:], a.TrackCount)
+	binary.LittleEndian.PutUint32(b[68:], a.IceCount)
+
+	pos := 72
+	for i := range a.Objects {
+		encodeObject(b[pos:pos+objectSize], &a.Objects[i])
+		pos += objectSize
+	}
+	for i := range a.Sounds {
+		o := &a.Sounds[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.Id, o.X, o.Y, o.Unk1
+		pos += soundSize
+	}
+	for i := range a.Snakes {
+		encodeSnake(b[pos:pos+snakeSize], &a.Snakes[i])
+		pos += snakeSize
+	}
+	for i := range a.ClearPipes {
+		encodeClearPipe(b[pos:pos+clearPipeSize], &a.ClearPipes[i])
+		pos += clearPipeSize
+	}
+	for i := range a.PiranhaCreepers {
+		o := &a.PiranhaCreepers[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.Unk1, o.Index, o.NodeCount, o.Unk2
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			node[0], node[1] = o.Nodes[n].Unk1, o.Nodes[n].Direction
+			binary.LittleEndian.PutUint16(node[2:], o.Nodes[n].Unk2)
+		}
+		pos += piranhaCreeperSize
+	}
+	for i := range a.ExclamationBlocks {
+		o := &a.ExclamationBlocks[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.Unk1, o.Index, o.NodeCount, o.Unk2
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			node[0], node[1] = o.Nodes[n].Unk1, o.Nodes[n].Direction
+			binary.LittleEndian.PutUint16(node[2:], o.Nodes[n].Unk2)
+		}
+		pos += exclamationBlockSize
+	}
+	for i := range a.TrackBlocks {
+		o := &a.TrackBlocks[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.Unk1, o.Index, o.NodeCount, o.Unk2
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			node[0], node[1] = o.Nodes[n].Unk1, o.Nodes[n].Direction
+			binary.LittleEndian.PutUint16(node[2:], o.Nodes[n].Unk2)
+		}
+		pos += trackBlockSize
+	}
+	for i := range a.Ground {
+		o := &a.Ground[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.X, o.Y, o.Id, o.BackgroundId
+		pos += groundSize
+	}
+	for i := range a.Tracks {
+		o := &a.Tracks[i]
+		binary.LittleEndian.PutUint16(b[pos:], o.Unk1)
+		b[pos+2], b[pos+3], b[pos+4], b[pos+5] = o.Flags, o.X, o.Y, o.Type
+		binary.LittleEndian.PutUint16(b[pos+6:], o.LId)
+		binary.LittleEndian.PutUint16(b[pos+8:], o.Unk2)
+		binary.LittleEndian.PutUint16(b[pos+10:], o.Unk3)
+		pos += trackSize
+	}
+	for i := range a.Icicles {
+		o := &a.Icicles[i]
+		b[pos], b[pos+1], b[pos+2], b[pos+3] = o.X, o.Y, o.Type, o.Unk1
+		pos += icicleSize
+	}
+	copy(b[pos:], a.Unk2[:])
+}
+
+func decodeLevelArea(a *LevelArea, b []byte) {
+	a.Theme = b[0]
+	a.AutoscrollType = b[1]
+	a.BoundaryType = b[2]
+	a.Orientation = b[3]
+	a.LiquidEndHeight = b[4]
+	a.LiquidType = b[5]
+	a.LiquidSpeed = b[6]
+	a.LiquidStartHeight = b[7]
+	a.BoundaryRight = binary.LittleEndian.Uint32(b[8:])
+	a.BoundaryTop = binary.LittleEndian.Uint32(b[12:])
+	a.BoundaryLeft = binary.LittleEndian.Uint32(b[16:])
+	a.BoundaryBottom = binary.LittleEndian.Uint32(b[20:])
+	a.UnkFlag = binary.LittleEndian.Uint32(b[24:])
+	a.ObjectCount = binary.LittleEndian.Uint32(b[28:])
+	a.SoundEffectCount = binary.LittleEndian.Uint32(b[32:])
+	a.SnakeBlockCount = binary.LittleEndian.Uint32(b[36:])
+	a.ClearPipeCount = binary.LittleEndian.Uint32(b[40:])
+	a.PiranhaCreeperCount = binary.LittleEndian.Uint32(b[44:])
+	a.ExclamationMarkBlockCount = binary.LittleEndian.Uint32(b[48:])
+	a.TrackBlockCount = binary.LittleEndian.Uint32(b[52:])
+	a.Unk1 = binary.LittleEndian.Uint32(b[56:])
+	a.GroundCount = binary.LittleEndian.Uint32(b[60:])
+	a.TrackCount = binary.LittleEndian.Uint32(b[64:])
+	a.IceCount = binary.LittleEndian.Uint32(b[68:])
+
+	pos := 72
+	for i := range a.Objects {
+		decodeObject(&a.Objects[i], b[pos:pos+objectSize])
+		pos += objectSize
+	}
+	for i := range a.Sounds {
+		o := &a.Sounds[i]
+		o.Id, o.X, o.Y, o.Unk1 = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		pos += soundSize
+	}
+	for i := range a.Snakes {
+		decodeSnake(&a.Snakes[i], b[pos:pos+snakeSize])
+		pos += snakeSize
+	}
+	for i := range a.ClearPipes {
+		decodeClearPipe(&a.ClearPipes[i], b[pos:pos+clearPipeSize])
+		pos += clearPipeSize
+	}
+	for i := range a.PiranhaCreepers {
+		o := &a.PiranhaCreepers[i]
+		o.Unk1, o.Index, o.NodeCount, o.Unk2 = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			o.Nodes[n] = PiranhaCreeperNode{node[0], node[1], binary.LittleEndian.Uint16(node[2:])}
+		}
+		pos += piranhaCreeperSize
+	}
+	for i := range a.ExclamationBlocks {
+		o := &a.ExclamationBlocks[i]
+		o.Unk1, o.Index, o.NodeCount, o.Unk2 = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			o.Nodes[n] = ExclamationBlockNode{node[0], node[1], binary.LittleEndian.Uint16(node[2:])}
+		}
+		pos += exclamationBlockSize
+	}
+	for i := range a.TrackBlocks {
+		o := &a.TrackBlocks[i]
+		o.Unk1, o.Index, o.NodeCount, o.Unk2 = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		for n := range o.Nodes {
+			node := b[pos+4+n*4:]
+			o.Nodes[n] = TrackBlockNode{node[0], node[1], binary.LittleEndian.Uint16(node[2:])}
+		}
+		pos += trackBlockSize
+	}
+	for i := range a.Ground {
+		o := &a.Ground[i]
+		o.X, o.Y, o.Id, o.BackgroundId = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		pos += groundSize
+	}
+	for i := range a.Tracks {
+		o := &a.Tracks[i]
+		o.Unk1 = binary.LittleEndian.Uint16(b[pos:])
+		o.Flags, o.X, o.Y, o.Type = b[pos+2], b[pos+3], b[pos+4], b[pos+5]
+		o.LId = binary.LittleEndian.Uint16(b[pos+6:])
+		o.Unk2 = binary.LittleEndian.Uint16(b[pos+8:])
+		o.Unk3 = binary.LittleEndian.Uint16(b[pos+10:])
+		pos += trackSize
+	}
+	for i := range a.Icicles {
+		o := &a.Icicles[i]
+		o.X, o.Y, o.Type, o.Unk1 = b[pos], b[pos+1], b[pos+2], b[pos+3]
+		pos += icicleSize
+	}
+	copy(a.Unk2[:], b[pos:])
+}
+
+func encodeObject(b []byte, o *Object) {
+	binary.LittleEndian.PutUint32(b[0:], o.X)
+	binary.LittleEndian.PutUint32(b[4:], o.Y)
+	binary.LittleEndian.PutUint16(b[8:], o.Unk1)
+	b[10] = o.Width
+	b[11] = o.Height
+	binary.LittleEndian.PutUint32(b[12:], o.Flag)
+	binary.LittleEndian.PutUint32(b[16:], o.CFlag)
+	binary.LittleEndian.PutUint32(b[20:], o.Ex)
+	binary.LittleEndian.PutUint16(b[24:], o.Id)
+	binary.LittleEndian.PutUint16(b[26:], o.CId)
+	binary.LittleEndian.PutUint16(b[28:], o.LId)
+	binary.LittleEndian.PutUint16(b[30:], o.SId)
+}
+
+func decodeObject(o *Object, b []byte) {
+	o.X = binary.LittleEndian.Uint32(b[0:])
+	o.Y = binary.LittleEndian.Uint32(b[4:])
+	o.Unk1 = binary.LittleEndian.Uint16(b[8:])
+	o.Width = b[10]
+	o.Height = b[11]
+	o.Flag = binary.LittleEndian.Uint32(b[12:])
+	o.CFlag = binary.LittleEndian.Uint32(b[16:])
+	o.Ex = binary.LittleEndian.Uint32(b[20:])
+	o.Id = binary.LittleEndian.Uint16(b[24:])
+	o.CId = binary.LittleEndian.Uint16(b[26:])
+	o.LId = binary.LittleEndian.Uint16(b[28:])
+	o.SId = binary.LittleEndian.Uint16(b[30:])
+}
+
+func encodeSnake(b []byte, o *Snake) {
+	b[0] = o.Index
+	b[1] = o.NodeCount
+	binary.LittleEndian.PutUint16(b[2:], o.Unk1)
+	for n := range o.Nodes {
+		node := b[4+n*8:]
+		binary.LittleEndian.PutUint16(node[0:], o.Nodes[n].Index)
+		binary.LittleEndian.PutUint16(node[2:], o.Nodes[n].Direction)
+		binary.LittleEndian.PutUint32(node[4:], o.Nodes[n].Id)
+	}
+}
+
+func decodeSnake(o *Snake, b []byte) {
+	o.Index = b[0]
+	o.NodeCount = b[1]
+	o.Unk1 = binary.LittleEndian.Uint16(b[2:])
+	for n := range o.Nodes {
+		node := b[4+n*8:]
+		o.Nodes[n] = SnakeNode{binary.LittleEndian.Uint16(node[0:]), binary.LittleEndian.Uint16(node[2:]), binary.LittleEndian.Uint32(node[4:])}
+	}
+}
+
+func encodeClearPipe(b []byte, o *ClearPipe) {
+	b[0] = o.Index
+	b[1] = o.NodeCount
+	binary.LittleEndian.PutUint16(b[2:], o.Unk)
+	for n := range o.Nodes {
+		node := b[4+n*8:]
+		c := &o.Nodes[n]
+		node[0], node[1], node[2], node[3] = c.Type, c.Index, c.X, c.Y
+		node[4], node[5], node[6], node[7] = c.Width, c.Height, c.Unk1, c.Direction
+	}
+}
+
+func decodeClearPipe(o *ClearPipe, b []byte) {
+	o.Index = b[0]
+	o.NodeCount = b[1]
+	o.Unk = binary.LittleEndian.Uint16(b[2:])
+	for n := range o.Nodes {
+		node := b[4+n*8:]
+		o.Nodes[n] = ClearPipeNode{node[0], node[1], node[2], node[3], node[4], node[5], node[6], node[7]}
+	}
+}
@@ -0,0 +1,195 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// The hand written codec must match binary.Read/binary.Write byte for byte
+func TestCodecMatchesBinary(t *testing.T) {
+	if size := binary.Size(&BCD{}); size != decryptedLevelSize {
+		t.Fatalf("binary.Size(BCD) = 0x%X, want 0x%X", size, decryptedLevelSize)
+	}
+	for seed := int64(0); seed < 4; seed++ {
+		level := newTestLevel(t, seed)
+
+		var want bytes.Buffer
+		if err := binary.Write(&want, binary.LittleEndian, level); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, decryptedLevelSize)
+		encodeBCD(got, level)
+		if !bytes.Equal(got, want.Bytes()) {
+			for i := range got {
+				if got[i] != want.Bytes()[i] {
+					t.Fatalf("seed %d: encodeBCD differs from binary.Write at 0x%X", seed, i)
+				}
+			}
+		}
+
+		var read, decoded BCD
+		if err := binary.Read(bytes.NewReader(got), binary.LittleEndian, &read); err != nil {
+			t.Fatal(err)
+		}
+		decodeBCD(&decoded, got)
+		if !reflect.DeepEqual(&read, &decoded) {
+			t.Fatalf("seed %d: decodeBCD differs from binary.Read", seed)
+		}
+	}
+}
+
+func encryptedTestLevels(t *testing.T) ([][]byte, []*BCD) {
+	var encrypted [][]byte
+	var levels []*BCD
+	for seed := int64(0); seed < 3; seed++ {
+		level := newTestLevel(t, seed)
+		buf, err := level.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted = append(encrypted, buf)
+		levels = append(levels, level)
+	}
+	return encrypted, levels
+}
+
+func TestLoadHeader(t *testing.T) {
+	encrypted, levels := encryptedTestLevels(t)
+	for i, buf := range encrypted {
+		h, err := LoadHeader(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		full, err := LoadBCD(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*h, full.Header) || !reflect.DeepEqual(*h, levels[i].Header) {
+			t.Fatalf("level %d: LoadHeader differs from LoadBCD", i)
+		}
+
+		header, err := DecryptLevelHeader(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := DecryptLevel(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(header, decrypted[:headerSize]) {
+			t.Fatalf("level %d: DecryptLevelHeader differs from DecryptLevel", i)
+		}
+	}
+
+	if _, err := LoadHeader(encrypted[0][:0x5c000-1]); err == nil {
+		t.Fatal("no error for a short level")
+	}
+}
+
+func TestVerifyLevel(t *testing.T) {
+	encrypted, _ := encryptedTestLevels(t)
+	buf := encrypted[0]
+	if err := VerifyLevel(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name   string
+		offset int
+	}{
+		{"body start", 0x10},
+		{"body end", 0x5BFCF},
+		{"crc", 8},
+		{"cmac", 0x5BFF0},
+		{"cmac end", 0x5BFFF},
+	} {
+		corrupt := bytes.Clone(buf)
+		corrupt[test.offset] ^= 1
+		if err := VerifyLevel(corrupt); err == nil {
+			t.Errorf("%s: flipped byte at 0x%X not detected", test.name, test.offset)
+		}
+	}
+	if err := VerifyLevel(buf[:0x5c000-1]); err == nil {
+		t.Error("no error for a short level")
+	}
+}
+
+// A Decoder must give the same result however many levels it decoded before
+func TestDecoderReuse(t *testing.T) {
+	encrypted, levels := encryptedTestLevels(t)
+	d := NewDecoder()
+	s := &BCD{}
+	h := &Header{}
+	for round := 0; round < 2; round++ {
+		for i, buf := range encrypted {
+			if err := d.Decode(buf, s); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s, levels[i]) {
+				t.Fatalf("round %d, level %d: Decode differs from the saved level", round, i)
+			}
+			if err := d.DecodeHeader(buf, h); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*h, levels[i].Header) {
+				t.Fatalf("round %d, level %d: DecodeHeader differs from the saved header", round, i)
+			}
+		}
+	}
+
+	corrupt := bytes.Clone(encrypted[0])
+	corrupt[0x100] ^= 1
+	if err := d.Decode(corrupt, s); err == nil {
+		t.Fatal("no error for a corrupt level")
+	}
+	// A failed decode doesn't break the next one
+	if err := d.Decode(encrypted[1], s); err != nil || !reflect.DeepEqual(s, levels[1]) {
+		t.Fatalf("decode after a failed one: %v", err)
+	}
+}
+
+func BenchmarkDecodeBCD(b *testing.B) {
+	buf := mustMarshal(b, newTestLevel(b, 1))
+	level := &BCD{}
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeBCD(level, buf)
+	}
+}
+
+func BenchmarkDecodeBCDBinaryRead(b *testing.B) {
+	buf := mustMarshal(b, newTestLevel(b, 1))
+	level := &BCD{}
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, level); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeBCD(b *testing.B) {
+	level := newTestLevel(b, 1)
+	buf := make([]byte, decryptedLevelSize)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encodeBCD(buf, level)
+	}
+}
+
+func BenchmarkEncodeBCDBinaryWrite(b *testing.B) {
+	level := newTestLevel(b, 1)
+	var buf bytes.Buffer
+	b.SetBytes(decryptedLevelSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := binary.Write(&buf, binary.LittleEndian, level); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
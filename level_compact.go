@@ -0,0 +1,293 @@
+package smm2_parsing
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Compact encoding of a decrypted BCD. The header and area headers are kept
+// as is, every fixed array only stores entries up to its last non-zero one
+// (usually its count) and fields are stored as varint deltas from the same
+// field of the previous entry. Entries past the counts are kept too, so
+// ExpandLevel always returns the exact bytes given to CompactLevel.
+//
+//	version byte
+//	header (0x200 bytes)
+//	for each area:
+//		area header (0x48 bytes)
+//		for each array: uvarint entries, then each entry's fields as varint deltas
+//		                (node arrays: uvarint nodes, then each node's fields)
+//		uvarint length, Unk2 without trailing zeros
+const compactVersion = 1
+
+// Field sizes of an entry, in bytes
+type compactLayout struct {
+	head  []int
+	node  []int // Fields of one node, nil if the entry has no nodes
+	nodes int
+}
+
+func (l *compactLayout) size() int {
+	size := 0
+	for _, f := range l.head {
+		size += f
+	}
+	for _, f := range l.node {
+		size += f * l.nodes
+	}
+	return size
+}
+
+var compactNodeContainer = []int{1, 1, 1, 1}
+
+// In the order of LevelArea
+var compactArrays = []struct {
+	capacity int
+	layout   compactLayout
+}{
+	{2600, compactLayout{head: []int{4, 4, 2, 1, 1, 4, 4, 4, 2, 2, 2, 2}}}, // Objects
+	{300, compactLayout{head: []int{1, 1, 1, 1}}},                          // Sounds
+	{5, compactLayout{head: []int{1, 1, 2}, node: []int{2, 2, 4}, nodes: 120}},
+	{200, compactLayout{head: []int{1, 1, 2}, node: []int{1, 1, 1, 1, 1, 1, 1, 1}, nodes: 36}},
+	{10, compactLayout{head: compactNodeContainer, node: []int{1, 1, 2}, nodes: 20}}, // Piranha creepers
+	{10, compactLayout{head: compactNodeContainer, node: []int{1, 1, 2}, nodes: 10}}, // Exclamation blocks
+	{10, compactLayout{head: compactNodeContainer, node: []int{1, 1, 2}, nodes: 10}}, // Track blocks
+	{4000, compactLayout{head: []int{1, 1, 1, 1}}},                                   // Ground
+	{1500, compactLayout{head: []int{2, 1, 1, 1, 1, 2, 2, 2}}},                       // Tracks
+	{300, compactLayout{head: []int{1, 1, 1, 1}}},                                    // Icicles
+}
+
+const levelAreaHeaderSize = 0x48
+
+func CompactLevel(decrypted []byte) ([]byte, error) {
+	if len(decrypted) != decryptedLevelSize {
+		return nil, fmt.Errorf("invalid buf size %d != %d", len(decrypted), decryptedLevelSize)
+	}
+
+	out := []byte{compactVersion}
+	out = append(out, decrypted[:headerSize]...)
+	for area := 0; area < 2; area++ {
+		b := decrypted[headerSize+area*levelAreaSize : headerSize+(area+1)*levelAreaSize]
+		out = append(out, b[:levelAreaHeaderSize]...)
+		pos := levelAreaHeaderSize
+		for _, array := range compactArrays {
+			size := array.layout.size()
+			out = compactArray(out, b[pos:pos+array.capacity*size], size, &array.layout)
+			pos += array.capacity * size
+		}
+		unk2 := trimZeros(b[pos:])
+		out = binary.AppendUvarint(out, uint64(len(unk2)))
+		out = append(out, unk2...)
+	}
+	return out, nil
+}
+
+func ExpandLevel(buf []byte) ([]byte, error) {
+	if len(buf) < 1+headerSize {
+		return nil, fmt.Errorf("compact level too short")
+	}
+	if buf[0] != compactVersion {
+		return nil, fmt.Errorf("unknown compact version %d", buf[0])
+	}
+
+	decrypted := make([]byte, decryptedLevelSize)
+	copy(decrypted, buf[1:1+headerSize])
+	r := &compactReader{buf: buf, pos: 1 + headerSize}
+	for area := 0; area < 2; area++ {
+		b := decrypted[headerSize+area*levelAreaSize : headerSize+(area+1)*levelAreaSize]
+		copy(b, r.bytes(levelAreaHeaderSize))
+		pos := levelAreaHeaderSize
+		for _, array := range compactArrays {
+			size := array.layout.size()
+			r.array(b[pos:pos+array.capacity*size], size, &array.layout)
+			pos += array.capacity * size
+		}
+		unk2Size := r.uvarint()
+		if unk2Size > uint64(len(b)-pos) {
+			r.fail("unk2 too long")
+		}
+		copy(b[pos:], r.bytes(int(unk2Size)))
+	}
+
+	if r.err == nil && r.pos != len(buf) {
+		r.fail("trailing data")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return decrypted, nil
+}
+
+func (s *BCD) MarshalCompact() ([]byte, error) {
+	decrypted, err := s.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return CompactLevel(decrypted)
+}
+
+func (s *BCD) UnmarshalCompact(buf []byte) error {
+	decrypted, err := ExpandLevel(buf)
+	if err != nil {
+		return err
+	}
+	return s.UnmarshalBinary(decrypted)
+}
+
+func trimZeros(b []byte) []byte {
+	end := len(b)
+	for end > 0 && b[end-1] == 0 {
+		end--
+	}
+	return b[:end]
+}
+
+// Number of size byte entries in b up to the last one that isn't all zeros
+func usedEntries(b []byte, size int) int {
+	return (len(trimZeros(b)) + size - 1) / size
+}
+
+func compactArray(out []byte, b []byte, size int, layout *compactLayout) []byte {
+	entries := usedEntries(b, size)
+	out = binary.AppendUvarint(out, uint64(entries))
+	prev := make([]uint64, len(layout.head))
+	prevNode := make([]uint64, len(layout.node))
+	for i := 0; i < entries; i++ {
+		entry := b[i*size : (i+1)*size]
+		out, entry = compactFields(out, entry, layout.head, prev)
+		if layout.node == nil {
+			continue
+		}
+
+		nodeSize := len(entry) / layout.nodes
+		nodes := usedEntries(entry, nodeSize)
+		out = binary.AppendUvarint(out, uint64(nodes))
+		for f := range prevNode {
+			prevNode[f] = 0
+		}
+		for n := 0; n < nodes; n++ {
+			out, _ = compactFields(out, entry[n*nodeSize:(n+1)*nodeSize], layout.node, prevNode)
+		}
+	}
+	return out
+}
+
+// Append fields as deltas from prev, returns the rest of b
+func compactFields(out []byte, b []byte, fields []int, prev []uint64) ([]byte, []byte) {
+	for f, width := range fields {
+		v := readField(b, width)
+		out = binary.AppendVarint(out, int64(v-prev[f]))
+		prev[f] = v
+		b = b[width:]
+	}
+	return out, b
+}
+
+func readField(b []byte, width int) uint64 {
+	switch width {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	}
+	return uint64(binary.LittleEndian.Uint32(b))
+}
+
+func writeField(b []byte, width int, v uint64) {
+	switch width {
+	case 1:
+		b[0] = uint8(v)
+	case 2:
+		binary.LittleEndian.PutUint16(b, uint16(v))
+	default:
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	}
+}
+
+// Reads a compact level, the first error sticks and later reads return zeros
+type compactReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *compactReader) fail(message string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid compact level at 0x%x: %s", r.pos, message)
+	}
+}
+
+func (r *compactReader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.buf)-r.pos {
+		r.fail("unexpected end")
+		return make([]byte, n)
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *compactReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.fail("invalid varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		r.fail("invalid varint")
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) array(b []byte, size int, layout *compactLayout) {
+	entries := r.uvarint()
+	if entries > uint64(len(b)/size) {
+		r.fail("too many entries")
+		return
+	}
+	prev := make([]uint64, len(layout.head))
+	prevNode := make([]uint64, len(layout.node))
+	for i := 0; i < int(entries) && r.err == nil; i++ {
+		entry := r.fields(b[i*size:(i+1)*size], layout.head, prev)
+		if layout.node == nil {
+			continue
+		}
+
+		nodeSize := len(entry) / layout.nodes
+		nodes := r.uvarint()
+		if nodes > uint64(layout.nodes) {
+			r.fail("too many nodes")
+			return
+		}
+		for f := range prevNode {
+			prevNode[f] = 0
+		}
+		for n := 0; n < int(nodes); n++ {
+			r.fields(entry[n*nodeSize:(n+1)*nodeSize], layout.node, prevNode)
+		}
+	}
+}
+
+func (r *compactReader) fields(b []byte, fields []int, prev []uint64) []byte {
+	for f, width := range fields {
+		v := prev[f] + uint64(r.varint())
+		writeField(b, width, v)
+		prev[f] = v
+		b = b[width:]
+	}
+	return b
+}
@@ -0,0 +1,118 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func compactTestLevels(t *testing.T) [][]byte {
+	var levels [][]byte
+	for seed := int64(0); seed < 3; seed++ {
+		levels = append(levels, newSyntheticLevel(t, seed))
+		levels = append(levels, mustMarshal(t, newTestLevel(t, seed)))
+
+		// Mostly empty with a few stray bytes past the counts and in the padding
+		r := rand.New(rand.NewSource(seed))
+		sparse := newSyntheticLevel(t, seed)
+		for i := 0; i < 20; i++ {
+			sparse[headerSize+r.Intn(len(sparse)-headerSize)] = byte(1 + r.Intn(255))
+		}
+		sparse[len(sparse)-1] = 0xFF
+		sparse[headerSize+levelAreaSize-1] = 0xFF
+		levels = append(levels, sparse)
+	}
+	return levels
+}
+
+func TestCompactRoundTrip(t *testing.T) {
+	for i, decrypted := range compactTestLevels(t) {
+		compact, err := CompactLevel(decrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expanded, err := ExpandLevel(compact)
+		if err != nil {
+			t.Fatalf("level %d: %v", i, err)
+		}
+		if !bytes.Equal(expanded, decrypted) {
+			t.Fatalf("level %d: bytes changed after a compact round trip", i)
+		}
+	}
+}
+
+func TestMarshalCompact(t *testing.T) {
+	for seed := int64(0); seed < 3; seed++ {
+		level := newTestLevel(t, seed)
+		compact, err := level.MarshalCompact()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &BCD{}
+		err = decoded.UnmarshalCompact(compact)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mustMarshal(t, decoded), mustMarshal(t, level)) {
+			t.Fatalf("seed %d: level changed after a compact round trip", seed)
+		}
+	}
+}
+
+func TestCompactLevelSize(t *testing.T) {
+	_, err := CompactLevel(make([]byte, decryptedLevelSize-1))
+	if err == nil {
+		t.Fatal("no error for a short level")
+	}
+}
+
+func TestExpandLevelTruncated(t *testing.T) {
+	compact, err := CompactLevel(newSyntheticLevel(t, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < len(compact); n++ {
+		_, err := ExpandLevel(compact[:n])
+		if err == nil {
+			t.Fatalf("no error for %d of %d bytes", n, len(compact))
+		}
+	}
+
+	_, err = ExpandLevel(append(compact, 0))
+	if err == nil {
+		t.Fatal("no error for trailing data")
+	}
+}
+
+func TestExpandLevelCorrupt(t *testing.T) {
+	compact, err := CompactLevel(newSyntheticLevel(t, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := bytes.Clone(compact)
+	version[0] = compactVersion + 1
+	_, err = ExpandLevel(version)
+	if err == nil {
+		t.Fatal("no error for an unknown version")
+	}
+
+	// Object count right after the first area header, past the capacity
+	entries := append(bytes.Clone(compact[:1+headerSize+levelAreaHeaderSize]), 0xFF, 0xFF, 0x03)
+	_, err = ExpandLevel(entries)
+	if err == nil || !strings.Contains(err.Error(), "too many entries") {
+		t.Fatalf("got %v, want too many entries", err)
+	}
+
+	// Corrupt input may still decode to some level, it only must not panic
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < 2000; i++ {
+		corrupt := bytes.Clone(compact)
+		for j := 0; j < 1+r.Intn(4); j++ {
+			corrupt[1+headerSize+r.Intn(len(corrupt)-1-headerSize)] = byte(r.Intn(256))
+		}
+		ExpandLevel(corrupt)
+		(&BCD{}).UnmarshalCompact(corrupt)
+	}
+}
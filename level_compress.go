@@ -0,0 +1,63 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"compress/zlib"
+	_ "embed"
+	"fmt"
+	"io"
+)
+
+// First byte of CompressLevel output, picks the dictionary so it can change
+// without breaking stored levels
+const levelDictVersion = 1
+
+// zlib only looks back 32KB so a whole empty BCD (mostly zeros) would be a
+// useless dictionary. Instead it holds the parts of an empty level that are
+// rarely zero: the header and the first 0x48 bytes of both areas. Dictionaries
+// are checked in so changes to newEmptyBCD can't break stored levels.
+//
+//go:embed level_dict_v1.bin
+var levelDictV1 []byte
+
+var levelDicts = map[byte][]byte{
+	1: levelDictV1,
+}
+
+// Compress a decrypted BCD with zlib at best compression and a preset
+// dictionary, smaller than Compress. Only DecompressLevel can read the result.
+func CompressLevel(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(levelDictVersion)
+	w, err := zlib.NewWriterLevelDict(buf, zlib.BestCompression, levelDicts[levelDictVersion])
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecompressLevel(buf []byte) ([]byte, error) {
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("empty buf")
+	}
+	dict, ok := levelDicts[buf[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown dictionary version %d", buf[0])
+	}
+
+	reader, err := zlib.NewReaderDict(bytes.NewReader(buf[1:]), dict)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
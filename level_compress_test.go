@@ -0,0 +1,124 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"hash/crc32"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// Stored levels depend on these bytes, a new dictionary needs a new version
+func TestLevelDictV1(t *testing.T) {
+	if len(levelDictV1) != 656 || crc32.ChecksumIEEE(levelDictV1) != 0xF3B21087 {
+		t.Errorf("dictionary 1 changed, %d bytes with crc %08X", len(levelDictV1), crc32.ChecksumIEEE(levelDictV1))
+	}
+}
+
+// Level shaped like one made in the game: objects on the tile grid with a
+// handful of Ids and flags, ground along the bottom and some platforms, the
+// rest of each area empty. Random bytes wouldn't compress at all.
+func newSyntheticLevel(t testing.TB, seed int64) []byte {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	level := newEmptyBCD()
+	ids := []ObjId{GOOMBA, KOOPA, COIN, BLOCK, QUESTION_BLOCK, HARD_BLOCK, PIPE, SPRING}
+	for _, area := range []*LevelArea{&level.OverWorld, &level.SubWorld} {
+		area.ObjectCount = uint32(50 + r.Intn(200))
+		for i := range area.Objects[:area.ObjectCount] {
+			area.Objects[i] = Object{
+				X:      uint32(r.Intn(240)*OBJECT_TILE_UNITS + OBJECT_TILE_UNITS/2),
+				Y:      uint32(r.Intn(27)*OBJECT_TILE_UNITS + OBJECT_TILE_UNITS/2),
+				Width:  1,
+				Height: 1,
+				Flag:   0x06000040,
+				Id:     uint16(ids[r.Intn(len(ids))]),
+				CId:    0xFFFF,
+			}
+		}
+
+		grid := NewGroundGrid(240, 27)
+		for x := 0; x < 240; x += 1 + r.Intn(12) {
+			grid.SetRect(image.Rect(x, 0, x+1+r.Intn(8), 1+r.Intn(3)), true)
+			if r.Intn(3) == 0 {
+				y := 4 + r.Intn(18)
+				grid.SetRect(image.Rect(x, y, x+2+r.Intn(6), y+1), true)
+			}
+		}
+		if err := area.SetGround(grid, nil); err != nil {
+			t.Fatal(err)
+		}
+		for i := range area.Ground[:area.GroundCount] {
+			area.Ground[i].Id = uint8(r.Intn(4))
+		}
+	}
+	return mustMarshal(t, level)
+}
+
+func TestCompressLevelRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 4; seed++ {
+		level := newSyntheticLevel(t, seed)
+		compressed, err := CompressLevel(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed[0] != levelDictVersion {
+			t.Errorf("version byte %d", compressed[0])
+		}
+		out, err := DecompressLevel(compressed)
+		if err != nil || !bytes.Equal(out, level) {
+			t.Errorf("seed %d: round trip changed the level, %v", seed, err)
+		}
+	}
+}
+
+// zlib at the same level without the dictionary, to tell the dictionary's
+// effect apart from the compression level
+func compressBestNoDict(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w, err := zlib.NewWriterLevel(buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func benchmarkCompress(b *testing.B, compress func([]byte) ([]byte, error)) {
+	levels := make([][]byte, 16)
+	for i := range levels {
+		levels[i] = newSyntheticLevel(b, int64(i))
+	}
+	b.SetBytes(decryptedLevelSize)
+	b.ResetTimer()
+	total := 0
+	for i := 0; i < b.N; i++ {
+		out, err := compress(levels[i%len(levels)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		total += len(out)
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "out-bytes/op")
+}
+
+// Default zlib level, no dictionary
+func BenchmarkCompress(b *testing.B) {
+	benchmarkCompress(b, Compress)
+}
+
+// Best compression, no dictionary
+func BenchmarkCompressBest(b *testing.B) {
+	benchmarkCompress(b, compressBestNoDict)
+}
+
+// Best compression with the dictionary
+func BenchmarkCompressLevel(b *testing.B) {
+	benchmarkCompress(b, CompressLevel)
+}
@@ -0,0 +1,326 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type DiffKind uint8
+
+const (
+	DIFF_CHANGED DiffKind = iota
+	DIFF_ADDED
+	DIFF_REMOVED
+	DIFF_MOVED
+)
+
+var diffKindNames = map[DiffKind]string{
+	DIFF_CHANGED: "changed",
+	DIFF_ADDED:   "added",
+	DIFF_REMOVED: "removed",
+	DIFF_MOVED:   "moved",
+}
+
+func (k DiffKind) String() string {
+	return enumString(diffKindNames, k)
+}
+
+type LevelChange struct {
+	Area  string // "header", "overworld" or "subworld"
+	Field string // Field name for scalars, array name such as "Objects" for entries
+	Kind  DiffKind
+	Old   string // Value or entry before, empty when added
+	New   string // Value or entry after, empty when removed
+}
+
+func (c LevelChange) String() string {
+	switch c.Kind {
+	case DIFF_ADDED:
+		return fmt.Sprintf("%s %s added: %s", c.Area, c.Field, c.New)
+	case DIFF_REMOVED:
+		return fmt.Sprintf("%s %s removed: %s", c.Area, c.Field, c.Old)
+	default:
+		return fmt.Sprintf("%s %s %s: %s -> %s", c.Area, c.Field, c.Kind, c.Old, c.New)
+	}
+}
+
+type LevelDiff struct {
+	Changes []LevelChange
+}
+
+func (d *LevelDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *LevelDiff) String() string {
+	var lines []string
+	for _, c := range d.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Semantic diff between two levels. Objects are matched by their contents,
+// objects that only differ in position are reported as moved and objects at
+// the same position with the same Id as changed. Ground is compared per cell,
+// the other arrays per entry with entries sharing an Index reported as changed.
+func DiffBCD(a, b *BCD) (*LevelDiff, error) {
+	docA := &jsonBCD{}
+	docB := &jsonBCD{}
+	for _, pair := range []struct {
+		doc   *jsonBCD
+		level *BCD
+	}{{docA, a}, {docB, b}} {
+		pair.doc.Header.fromHeader(&pair.level.Header)
+		err := pair.doc.OverWorld.fromLevelArea(&pair.level.OverWorld)
+		if err != nil {
+			return nil, fmt.Errorf("overworld: %v", err)
+		}
+		err = pair.doc.SubWorld.fromLevelArea(&pair.level.SubWorld)
+		if err != nil {
+			return nil, fmt.Errorf("subworld: %v", err)
+		}
+	}
+
+	d := &LevelDiff{}
+	d.diffScalars("header", reflect.ValueOf(docA.Header), reflect.ValueOf(docB.Header))
+	d.diffArea("overworld", &docA.OverWorld, &docB.OverWorld)
+	d.diffArea("subworld", &docA.SubWorld, &docB.SubWorld)
+	return d, nil
+}
+
+func (d *LevelDiff) add(area string, field string, kind DiffKind, old string, new string) {
+	d.Changes = append(d.Changes, LevelChange{area, field, kind, old, new})
+}
+
+func (d *LevelDiff) diffScalars(area string, a reflect.Value, b reflect.Value) {
+	for i := 0; i < a.NumField(); i++ {
+		name := a.Type().Field(i).Name
+		fieldA := a.Field(i)
+		fieldB := b.Field(i)
+		switch {
+		case fieldA.Kind() == reflect.Slice && fieldA.Type().Elem().Kind() == reflect.Struct:
+			continue
+		case fieldA.Kind() == reflect.Map:
+			seen := map[string]bool{}
+			var keys []string
+			for _, key := range append(fieldA.MapKeys(), fieldB.MapKeys()...) {
+				if !seen[key.String()] {
+					seen[key.String()] = true
+					keys = append(keys, key.String())
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				oldValue := fieldA.MapIndex(reflect.ValueOf(key))
+				newValue := fieldB.MapIndex(reflect.ValueOf(key))
+				if !oldValue.IsValid() || !newValue.IsValid() || !bytes.Equal(oldValue.Bytes(), newValue.Bytes()) {
+					d.add(area, name+"."+key, DIFF_CHANGED, formatDiffValue(oldValue), formatDiffValue(newValue))
+				}
+			}
+		default:
+			oldValue := formatDiffValue(fieldA)
+			newValue := formatDiffValue(fieldB)
+			if oldValue != newValue {
+				d.add(area, name, DIFF_CHANGED, oldValue, newValue)
+			}
+		}
+	}
+}
+
+func formatDiffValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return formatTextValue(v)
+}
+
+func (d *LevelDiff) diffArea(area string, a *jsonLevelArea, b *jsonLevelArea) {
+	d.diffScalars(area, reflect.ValueOf(*a), reflect.ValueOf(*b))
+	d.diffObjects(area, a.Objects, b.Objects)
+	d.diffGround(area, a.Ground, b.Ground)
+
+	va := reflect.ValueOf(*a)
+	vb := reflect.ValueOf(*b)
+	for _, name := range []string{"Sounds", "Snakes", "ClearPipes", "PiranhaCreepers", "ExclamationBlocks", "TrackBlocks", "Tracks", "Icicles"} {
+		d.diffEntries(area, name, va.FieldByName(name), vb.FieldByName(name))
+	}
+}
+
+func describeObject(o jsonObject) string {
+	w := &bytes.Buffer{}
+	writeTextObject(w, &o)
+	return strings.TrimSpace(w.String())
+}
+
+func (d *LevelDiff) diffObjects(area string, a []jsonObject, b []jsonObject) {
+	// Drop everything that is unchanged
+	added := removeMatched(b, a)
+	removed := removeMatched(a, b)
+
+	// Same object somewhere else
+	byContents := map[jsonObject][]int{}
+	for i, o := range removed {
+		key := o
+		key.X, key.Y = 0, 0
+		byContents[key] = append(byContents[key], i)
+	}
+	matched := make([]bool, len(removed))
+	var remaining []jsonObject
+	for _, o := range added {
+		key := o
+		key.X, key.Y = 0, 0
+		if candidates := byContents[key]; len(candidates) > 0 {
+			i := candidates[0]
+			byContents[key] = candidates[1:]
+			matched[i] = true
+			d.add(area, "Objects", DIFF_MOVED, describeObject(removed[i]), describeObject(o))
+			continue
+		}
+		remaining = append(remaining, o)
+	}
+
+	// Same object in the same place with different settings
+	type position struct {
+		x, y uint32
+		id   ObjId
+	}
+	byPosition := map[position][]int{}
+	for i, o := range removed {
+		if !matched[i] {
+			key := position{o.X, o.Y, o.Id}
+			byPosition[key] = append(byPosition[key], i)
+		}
+	}
+	for _, o := range remaining {
+		key := position{o.X, o.Y, o.Id}
+		if candidates := byPosition[key]; len(candidates) > 0 {
+			i := candidates[0]
+			byPosition[key] = candidates[1:]
+			matched[i] = true
+			d.add(area, "Objects", DIFF_CHANGED, describeObject(removed[i]), describeObject(o))
+			continue
+		}
+		d.add(area, "Objects", DIFF_ADDED, "", describeObject(o))
+	}
+
+	for i, o := range removed {
+		if !matched[i] {
+			d.add(area, "Objects", DIFF_REMOVED, describeObject(o), "")
+		}
+	}
+}
+
+// Entries of a that don't have an identical counterpart in b, in order
+func removeMatched[T comparable](a []T, b []T) []T {
+	counts := map[T]int{}
+	for _, o := range b {
+		counts[o]++
+	}
+	var out []T
+	for _, o := range a {
+		if counts[o] > 0 {
+			counts[o]--
+		} else {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func (d *LevelDiff) diffGround(area string, a []Ground, b []Ground) {
+	// Drop everything that is unchanged first, a cell can have more than one entry
+	added := removeMatched(b, a)
+	removed := removeMatched(a, b)
+
+	type cell struct{ x, y uint8 }
+	byCell := map[cell][]int{}
+	for i, g := range added {
+		byCell[cell{g.X, g.Y}] = append(byCell[cell{g.X, g.Y}], i)
+	}
+
+	describe := func(g Ground) string {
+		return fmt.Sprintf("x=%d y=%d id=%d backgroundid=%d", g.X, g.Y, g.Id, g.BackgroundId)
+	}
+	matched := make([]bool, len(added))
+	for _, g := range removed {
+		if candidates := byCell[cell{g.X, g.Y}]; len(candidates) > 0 {
+			i := candidates[0]
+			byCell[cell{g.X, g.Y}] = candidates[1:]
+			matched[i] = true
+			d.add(area, "Ground", DIFF_CHANGED, describe(g), describe(added[i]))
+			continue
+		}
+		d.add(area, "Ground", DIFF_REMOVED, describe(g), "")
+	}
+	for i, g := range added {
+		if !matched[i] {
+			d.add(area, "Ground", DIFF_ADDED, "", describe(g))
+		}
+	}
+}
+
+func describeEntry(name string, v reflect.Value) string {
+	w := &bytes.Buffer{}
+	writeTextEntry(w, sectionTag(strings.ToLower(name)), v)
+	return strings.Join(strings.Fields(w.String()), " ")
+}
+
+func (d *LevelDiff) diffEntries(area string, name string, a reflect.Value, b reflect.Value) {
+	describedA := make([]string, a.Len())
+	for i := range describedA {
+		describedA[i] = describeEntry(name, a.Index(i))
+	}
+	describedB := make([]string, b.Len())
+	for i := range describedB {
+		describedB[i] = describeEntry(name, b.Index(i))
+	}
+
+	removedIdx := removeMatchedIndexes(describedA, describedB)
+	addedIdx := removeMatchedIndexes(describedB, describedA)
+
+	// Entries that share an Index are the same pipe or path that was edited
+	hasIndex := a.Type().Elem().Kind() == reflect.Struct && reflect.New(a.Type().Elem()).Elem().FieldByName("Index").IsValid()
+	matched := map[int]bool{}
+	for _, ib := range addedIdx {
+		found := -1
+		if hasIndex {
+			for _, ia := range removedIdx {
+				if !matched[ia] && a.Index(ia).FieldByName("Index").Uint() == b.Index(ib).FieldByName("Index").Uint() {
+					found = ia
+					break
+				}
+			}
+		}
+		if found >= 0 {
+			matched[found] = true
+			d.add(area, name, DIFF_CHANGED, describedA[found], describedB[ib])
+		} else {
+			d.add(area, name, DIFF_ADDED, "", describedB[ib])
+		}
+	}
+	for _, ia := range removedIdx {
+		if !matched[ia] {
+			d.add(area, name, DIFF_REMOVED, describedA[ia], "")
+		}
+	}
+}
+
+func removeMatchedIndexes(a []string, b []string) []int {
+	counts := map[string]int{}
+	for _, s := range b {
+		counts[s]++
+	}
+	var out []int
+	for i, s := range a {
+		if counts[s] > 0 {
+			counts[s]--
+		} else {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package smm2_parsing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffKindString(t *testing.T) {
+	if DIFF_MOVED.String() != "moved" {
+		t.Fatalf("got %s", DIFF_MOVED)
+	}
+	// Unknown kinds used to index past the end of the names
+	if DiffKind(99).String() != "99" {
+		t.Fatalf("got %s", DiffKind(99))
+	}
+}
+
+func diffTestLevels(t *testing.T) (*BCD, *BCD) {
+	a := newTestLevel(t, 0)
+	if a.OverWorld.ObjectCount < 2 || a.OverWorld.ObjectCount == uint32(len(a.OverWorld.Objects)) || a.OverWorld.GroundCount < 2 || a.OverWorld.TrackCount < 2 {
+		t.Fatalf("seed 0 doesn't have the entries the diff tests need")
+	}
+	b := new(BCD)
+	*b = *a
+	return a, b
+}
+
+func diffOne(t *testing.T, a *BCD, b *BCD) LevelChange {
+	t.Helper()
+	d, err := DiffBCD(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing a count also moves an entry in or out of the bytes past the
+	// count, which are compared as residual data
+	var changes []LevelChange
+	for _, c := range d.Changes {
+		if !strings.HasPrefix(c.Field, "Residual.") {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1:\n%s", len(changes), d)
+	}
+	return changes[0]
+}
+
+func TestDiffSame(t *testing.T) {
+	a, b := diffTestLevels(t)
+	d, err := DiffBCD(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Fatalf("changes between identical levels:\n%s", d)
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	a, b := diffTestLevels(t)
+	b.OverWorld.Objects[0].X += OBJECT_TILE_UNITS
+	c := diffOne(t, a, b)
+	if c.Area != "overworld" || c.Field != "Objects" || c.Kind != DIFF_MOVED {
+		t.Errorf("moved object: got %s", c)
+	}
+
+	a, b = diffTestLevels(t)
+	b.OverWorld.Objects[0].Flag ^= OBJFLAG_WINGS
+	c = diffOne(t, a, b)
+	if c.Kind != DIFF_CHANGED || !strings.Contains(c.Old+c.New, "wings") {
+		t.Errorf("changed object: got %s", c)
+	}
+
+	a, b = diffTestLevels(t)
+	b.OverWorld.ObjectCount++
+	c = diffOne(t, a, b)
+	if c.Kind != DIFF_ADDED || c.Old != "" || c.New == "" {
+		t.Errorf("added object: got %s", c)
+	}
+
+	a, b = diffTestLevels(t)
+	b.OverWorld.ObjectCount--
+	c = diffOne(t, a, b)
+	if c.Kind != DIFF_REMOVED || c.Old == "" || c.New != "" {
+		t.Errorf("removed object: got %s", c)
+	}
+
+	// Swapping two objects in the array isn't a change
+	a, b = diffTestLevels(t)
+	b.OverWorld.Objects[0], b.OverWorld.Objects[1] = b.OverWorld.Objects[1], b.OverWorld.Objects[0]
+	d, err := DiffBCD(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("reordered objects reported as changes:\n%s", d)
+	}
+}
+
+func TestDiffGround(t *testing.T) {
+	// Two entries on one cell used to show up as changes between identical levels
+	a, b := diffTestLevels(t)
+	a.OverWorld.Ground[1] = a.OverWorld.Ground[0]
+	a.OverWorld.Ground[1].Id++
+	b.OverWorld.Ground[1] = a.OverWorld.Ground[1]
+	d, err := DiffBCD(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Fatalf("changes between identical levels:\n%s", d)
+	}
+
+	a, b = diffTestLevels(t)
+	b.OverWorld.Ground[0].Id++
+	c := diffOne(t, a, b)
+	if c.Field != "Ground" || c.Kind != DIFF_CHANGED {
+		t.Errorf("changed ground: got %s", c)
+	}
+
+	a, b = diffTestLevels(t)
+	b.OverWorld.GroundCount--
+	c = diffOne(t, a, b)
+	if c.Field != "Ground" || c.Kind != DIFF_REMOVED {
+		t.Errorf("removed ground: got %s", c)
+	}
+}
+
+// Tracks have no Index, an edited track is removed and added
+func TestDiffTracks(t *testing.T) {
+	a, b := diffTestLevels(t)
+	b.OverWorld.Tracks[0].Type++
+	d, err := DiffBCD(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[DiffKind]int{}
+	for _, c := range d.Changes {
+		if c.Field != "Tracks" {
+			t.Errorf("unexpected change %s", c)
+		}
+		kinds[c.Kind]++
+	}
+	if len(d.Changes) != 2 || kinds[DIFF_ADDED] != 1 || kinds[DIFF_REMOVED] != 1 {
+		t.Errorf("changed track:\n%s", d)
+	}
+}
+
+func TestDiffHeaderEnums(t *testing.T) {
+	a, b := diffTestLevels(t)
+	b.Header.GameStyle = STYLE_3W
+	c := diffOne(t, a, b)
+	if c.Area != "header" || c.Field != "GameStyle" || c.Old != "M3" || c.New != "3W" {
+		t.Errorf("game style: got %s", c)
+	}
+
+	a, b = diffTestLevels(t)
+	a.SubWorld.Theme = uint8(OVERWORLD)
+	b.SubWorld.Theme = uint8(CASTLE)
+	c = diffOne(t, a, b)
+	if c.Area != "subworld" || c.Field != "Theme" || c.Old != "OVERWORLD" || c.New != "CASTLE" {
+		t.Errorf("theme: got %s", c)
+	}
+}
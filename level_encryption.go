@@ -69,71 +69,65 @@ func DecryptLevel(buf []byte) ([]byte, error) {
 		return []byte{}, fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
 	}
 
+	decrypted := make([]byte, 0x5BFC0)
+	err := decryptLevelInto(decrypted, buf, &bytes.Buffer{})
+	if err != nil {
+		return nil, err
+	}
+	return decrypted, nil
+}
+
+// Decrypt the 0x5c000 byte level in buf into dst, dst may be buf[0x10:0x5BFD0]
+// to decrypt in place. keyBuf is scratch space for the keys.
+func decryptLevelInto(dst []byte, buf []byte, keyBuf *bytes.Buffer) error {
 	end := 0x5BFD0
-	writer := new(bytes.Buffer)
 
 	// Create random instance
-	r := &Random{
-		binary.LittleEndian.Uint32(buf[end+0x10 : end+0x14]),
-		binary.LittleEndian.Uint32(buf[end+0x14 : end+0x18]),
-		binary.LittleEndian.Uint32(buf[end+0x18 : end+0x1C]),
-		binary.LittleEndian.Uint32(buf[end+0x1C : end+0x20]),
-	}
+	r := newRandomFromSeed(buf[end+0x10 : end+0x20])
 
 	cmacWant := buf[end+0x20 : end+0x30]
 	crcWant := buf[8:12]
 
 	// Construct AES instance
-	aesKey := new(bytes.Buffer)
-	createKey(r, bcdTable, 0x10, aesKey)
+	keyBuf.Reset()
+	createKey(r, bcdTable, 0x10, keyBuf)
 
-	aesBlock, err := aes.NewCipher(aesKey.Bytes())
+	aesBlock, err := aes.NewCipher(keyBuf.Bytes())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	aesMode := cipher.NewCBCDecrypter(aesBlock, buf[end:end+0x10])
-	decrypted := make([]byte, 0x5BFC0)
-	aesMode.CryptBlocks(decrypted, buf[0x10:0x5BFD0])
+	aesMode.CryptBlocks(dst, buf[0x10:0x5BFD0])
 
 	// crc check
-	if crc32.ChecksumIEEE(decrypted) != binary.LittleEndian.Uint32(crcWant) {
-		return nil, fmt.Errorf("crc invalid")
+	if crc32.ChecksumIEEE(dst) != binary.LittleEndian.Uint32(crcWant) {
+		return fmt.Errorf("crc invalid")
 	}
 
 	// cmac check
-	cmacKey := new(bytes.Buffer)
-	createKey(r, bcdTable, 0x10, cmacKey)
-	cmacBlock, err := aes.NewCipher(cmacKey.Bytes())
+	keyBuf.Reset()
+	createKey(r, bcdTable, 0x10, keyBuf)
+	cmacBlock, err := aes.NewCipher(keyBuf.Bytes())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	cmacDigest, err := cmac.Sum(decrypted, cmacBlock, 0x10)
+	cmacDigest, err := cmac.Sum(dst, cmacBlock, 0x10)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if !bytes.Equal(cmacDigest, cmacWant) {
-		return nil, fmt.Errorf("cmac invalid")
-	}
-
-	if false {
-		// write bcd header
-		_, err = writer.Write(buf[:0x10])
-		if err != nil {
-			return nil, err
-		}
+		return fmt.Errorf("cmac invalid")
 	}
-
-	// Decrypted course data
-	_, err = writer.Write(decrypted)
-	if err != nil {
-		return nil, err
-	}
-
-	return writer.Bytes(), nil
+	return nil
 }
 
 func EncryptLevel(buf []byte) ([]byte, error) {
+	return EncryptLevelWithOptions(buf, nil)
+}
+
+// EncryptLevel with the seed and IV from opts, nil uses crypto/rand
+func EncryptLevelWithOptions(buf []byte, opts *EncryptOptions) ([]byte, error) {
 	var withoutBcdHeader bool
 
 	if len(buf) == 0x5BFD0-0x10 {
@@ -144,98 +138,74 @@ func EncryptLevel(buf []byte) ([]byte, error) {
 		return []byte{}, fmt.Errorf("invalid buf size %d != %d (%d)", len(buf), 0x5BFD0, 0x5BFC0-0x10)
 	}
 
-	var reader *bytes.Reader
+	out := make([]byte, 0x5c000)
 	if withoutBcdHeader {
-		reader = bytes.NewReader(buf)
+		copy(out[0x10:0x5BFD0], buf)
+		putBcdHeader(out)
 	} else {
-		reader = bytes.NewReader(buf[0x10:])
+		copy(out, buf)
+		binary.LittleEndian.PutUint32(buf[0x8:(0x8+4)], crc32.ChecksumIEEE(buf[0x10:])) // shouldn't be needed, only when modifing course data
+		binary.LittleEndian.PutUint32(out[0x8:(0x8+4)], crc32.ChecksumIEEE(buf[0x10:]))
 	}
 
-	decrypted := make([]byte, 0x5BFC0)
-	_, err := io.ReadFull(reader, decrypted)
+	err := encryptLevelInPlace(out, opts)
 	if err != nil {
 		return nil, err
 	}
+	return out, nil
+}
 
-	writer := new(bytes.Buffer)
+// Write the 0x10 byte BCD header for the decrypted level in buf[0x10:0x5BFD0]
+func putBcdHeader(buf []byte) {
+	binary.LittleEndian.PutUint32(buf[0:4], 0x1)
+	binary.LittleEndian.PutUint16(buf[4:6], 0x10)
+	binary.LittleEndian.PutUint16(buf[6:8], 0x0)
+	binary.LittleEndian.PutUint32(buf[8:12], crc32.ChecksumIEEE(buf[0x10:0x5BFD0]))
+	copy(buf[12:16], "SCDL")
+}
 
-	if withoutBcdHeader {
-		var data = []any{
-			uint32(0x1),
-			uint16(0x10),
-			uint16(0x0),
-			uint32(crc32.ChecksumIEEE(decrypted)),
-			uint8(0x53),
-			uint8(0x43),
-			uint8(0x44),
-			uint8(0x4C),
-		}
-		for _, v := range data {
-			err := binary.Write(writer, binary.LittleEndian, v)
-			if err != nil {
-				return nil, err
-			}
-		}
-	} else {
-		binary.LittleEndian.PutUint32(buf[0x8:(0x8+4)], crc32.ChecksumIEEE(decrypted)) // shouldn't be needed, only when modifing course data
-		writer.Write(buf[:0x10])
-	}
+// Encrypt the decrypted level in buf[0x10:0x5BFD0] in place and fill in the
+// IV, seed and CMAC after it, buf is a whole 0x5c000 byte level
+func encryptLevelInPlace(buf []byte, opts *EncryptOptions) error {
+	end := 0x5BFD0
+	decrypted := buf[0x10:end]
 
-	// Technically random bytes, we make it deterministic here
-	randomSeed := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
-	r := &Random{
-		binary.LittleEndian.Uint32(randomSeed[0:4]),
-		binary.LittleEndian.Uint32(randomSeed[4:8]),
-		binary.LittleEndian.Uint32(randomSeed[8:12]),
-		binary.LittleEndian.Uint32(randomSeed[12:16]),
+	randomSeed, err := opts.randomBytes(0x10)
+	if err != nil {
+		return err
+	}
+	r := newRandomFromSeed(randomSeed)
+	aesIv, err := opts.randomBytes(0x10)
+	if err != nil {
+		return err
 	}
-	// Also random, but deterministic here
-	aesIv := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
 
 	aesKey := new(bytes.Buffer)
 	createKey(r, bcdTable, 0x10, aesKey)
 	aesBlock, err := aes.NewCipher(aesKey.Bytes())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	aesMode := cipher.NewCBCEncrypter(aesBlock, aesIv)
-	encrypted := make([]byte, 0x5BFC0)
-	aesMode.CryptBlocks(encrypted, decrypted)
-
+	// The CMAC is over the decrypted level, so it comes before encrypting
 	cmacKey := new(bytes.Buffer)
 	createKey(r, bcdTable, 0x10, cmacKey)
 	cmacBlock, err := aes.NewCipher(cmacKey.Bytes())
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	cmacDigest, err := cmac.Sum(decrypted, cmacBlock, 0x10)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	_, err = writer.Write(encrypted)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = writer.Write(aesIv)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = writer.Write(randomSeed)
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = writer.Write(cmacDigest)
-	if err != nil {
-		return nil, err
-	}
+	aesMode := cipher.NewCBCEncrypter(aesBlock, aesIv)
+	aesMode.CryptBlocks(decrypted, decrypted)
 
-	return writer.Bytes(), nil
+	copy(buf[end:end+0x10], aesIv)
+	copy(buf[end+0x10:end+0x20], randomSeed)
+	copy(buf[end+0x20:end+0x30], cmacDigest)
+	return nil
 }
 
 func DecryptReplay(buf []byte) ([]byte, error) {
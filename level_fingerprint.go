@@ -0,0 +1,104 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// Perceptual fingerprint of a level's contents, the header (name, description,
+// upload state...) is not part of it so re-uploads with another name still
+// match. Hash is a SimHash over object types, quantized object positions and
+// coarse ground shapes, small edits only flip a few of its bits.
+type LevelFingerprint struct {
+	Hash      uint64
+	Histogram map[ObjId]uint32 // Objects of each type over both areas
+	Ground    uint32           // Ground cells over both areas
+}
+
+// Positions are quantized to blocks of this many tiles
+const fingerprintCellSize = 4
+
+func FingerprintBCD(level *BCD) (*LevelFingerprint, error) {
+	f := &LevelFingerprint{Histogram: map[ObjId]uint32{}}
+	var weights [64]int
+
+	addFeature := func(weight int, format string, args ...any) {
+		h := fnv.New64a()
+		fmt.Fprintf(h, format, args...)
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<i) != 0 {
+				weights[i] += weight
+			} else {
+				weights[i] -= weight
+			}
+		}
+	}
+
+	for areaIndex, area := range []*LevelArea{&level.OverWorld, &level.SubWorld} {
+		if int(area.ObjectCount) > len(area.Objects) || int(area.GroundCount) > len(area.Ground) {
+			return nil, fmt.Errorf("area %d counts out of range", areaIndex)
+		}
+
+		addFeature(4, "theme:%d:%d", areaIndex, area.Theme)
+
+		for _, o := range area.Objects[:area.ObjectCount] {
+			f.Histogram[ObjId(o.Id)]++
+			tileX := o.X / OBJECT_TILE_UNITS / fingerprintCellSize
+			tileY := o.Y / OBJECT_TILE_UNITS / fingerprintCellSize
+			addFeature(2, "position:%d:%d:%d:%d", areaIndex, o.Id, tileX, tileY)
+		}
+
+		cells := map[[2]uint8]int{}
+		for _, g := range area.Ground[:area.GroundCount] {
+			f.Ground++
+			cells[[2]uint8{g.X / fingerprintCellSize, g.Y / fingerprintCellSize}]++
+		}
+		for cell, count := range cells {
+			// Mostly filled and partly filled blocks count as different shapes
+			addFeature(1, "ground:%d:%d:%d:%d", areaIndex, cell[0], cell[1], count*4/(fingerprintCellSize*fingerprintCellSize+1))
+		}
+	}
+
+	// Once per type, per object the type counts outweigh the positions and
+	// levels using the same objects hash alike
+	for id := range f.Histogram {
+		addFeature(1, "object:%d", id)
+	}
+
+	for i, weight := range weights {
+		if weight > 0 {
+			f.Hash |= 1 << i
+		}
+	}
+	return f, nil
+}
+
+// Similarity between 0 and 1, the average of the SimHash bits in common and the
+// cosine similarity of the object histograms
+func (f *LevelFingerprint) Similarity(other *LevelFingerprint) float64 {
+	hashSimilarity := 1 - float64(bits.OnesCount64(f.Hash^other.Hash))/64
+
+	dot := float64(f.Ground) * float64(other.Ground)
+	normA := float64(f.Ground) * float64(f.Ground)
+	normB := float64(other.Ground) * float64(other.Ground)
+	for id, count := range f.Histogram {
+		dot += float64(count) * float64(other.Histogram[id])
+		normA += float64(count) * float64(count)
+	}
+	for _, count := range other.Histogram {
+		normB += float64(count) * float64(count)
+	}
+
+	histogramSimilarity := 1.0
+	if normA != 0 || normB != 0 {
+		histogramSimilarity = 0
+		if normA != 0 && normB != 0 {
+			histogramSimilarity = dot / (math.Sqrt(normA) * math.Sqrt(normB))
+		}
+	}
+
+	return (hashSimilarity + histogramSimilarity) / 2
+}
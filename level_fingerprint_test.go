@@ -0,0 +1,98 @@
+package smm2_parsing
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func fingerprintTestLevel(t *testing.T, seed int64) *BCD {
+	t.Helper()
+	level := &BCD{}
+	if err := level.UnmarshalBinary(newSyntheticLevel(t, seed)); err != nil {
+		t.Fatal(err)
+	}
+	return level
+}
+
+func mustFingerprint(t *testing.T, level *BCD) *LevelFingerprint {
+	t.Helper()
+	f, err := FingerprintBCD(level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func hashDistance(a, b *LevelFingerprint) int {
+	return bits.OnesCount64(a.Hash ^ b.Hash)
+}
+
+func TestFingerprintIgnoresHeader(t *testing.T) {
+	level := fingerprintTestLevel(t, 0)
+	before := mustFingerprint(t, level)
+	copy(level.Header.Name[:], EncodeToUCS2("Another name\x00"))
+	copy(level.Header.Description[:], EncodeToUCS2("Another description\x00"))
+	after := mustFingerprint(t, level)
+	if before.Hash != after.Hash || before.Similarity(after) != 1 {
+		t.Fatalf("renaming changed the fingerprint: %016x -> %016x", before.Hash, after.Hash)
+	}
+}
+
+// Small edits only flip a few bits, unrelated levels about half of them
+func TestFingerprintDistance(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		level := fingerprintTestLevel(t, seed)
+		original := mustFingerprint(t, level)
+
+		moved := new(BCD)
+		*moved = *level
+		moved.OverWorld.Objects[0].X += 20 * OBJECT_TILE_UNITS
+		if d := hashDistance(original, mustFingerprint(t, moved)); d > 6 {
+			t.Errorf("seed %d: moving one object flipped %d bits", seed, d)
+		}
+
+		added := new(BCD)
+		*added = *level
+		added.OverWorld.Objects[added.OverWorld.ObjectCount] = added.OverWorld.Objects[0]
+		added.OverWorld.Objects[added.OverWorld.ObjectCount].Y += 8 * OBJECT_TILE_UNITS
+		added.OverWorld.ObjectCount++
+		if d := hashDistance(original, mustFingerprint(t, added)); d > 6 {
+			t.Errorf("seed %d: adding one object flipped %d bits", seed, d)
+		}
+
+		other := mustFingerprint(t, fingerprintTestLevel(t, seed+100))
+		if d := hashDistance(original, other); d < 16 {
+			t.Errorf("seed %d: unrelated level only %d bits away", seed, d)
+		}
+	}
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	empty := mustFingerprint(t, newEmptyBCD())
+	fingerprints := []*LevelFingerprint{empty}
+	for seed := int64(0); seed < 5; seed++ {
+		fingerprints = append(fingerprints, mustFingerprint(t, fingerprintTestLevel(t, seed)))
+	}
+	for i, a := range fingerprints {
+		for j, b := range fingerprints {
+			similarity := a.Similarity(b)
+			if similarity < 0 || similarity > 1 {
+				t.Errorf("Similarity(%d, %d) = %f", i, j, similarity)
+			}
+			if i == j && similarity != 1 {
+				t.Errorf("Similarity(%d, %d) = %f for identical levels", i, j, similarity)
+			}
+			if i != j && similarity == 1 {
+				t.Errorf("Similarity(%d, %d) = 1 for different levels", i, j)
+			}
+		}
+	}
+}
+
+func TestFingerprintCounts(t *testing.T) {
+	level := newEmptyBCD()
+	level.OverWorld.ObjectCount = uint32(len(level.OverWorld.Objects) + 1)
+	if _, err := FingerprintBCD(level); err == nil {
+		t.Fatal("no error for an object count out of range")
+	}
+}
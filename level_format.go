@@ -33,7 +33,7 @@ type Header struct {
 	UploadId                uint64
 	GameVersion             uint32
 	Unk1                    [0xBD]byte
-	GameStyle               [0x2]byte // Game style: M1, M3, MW, WU, 3W
+	GameStyle               GameStyle // Game style: M1, M3, MW, WU, 3W
 	Unk2                    uint8
 	Name                    [0x42]byte // Course name, null-terminated, UCS-2
 	Description             [0xCA]byte //	Course description, null-terminated, UCS-2 (game only lets you enter up to 75, but there's space for 100)
@@ -208,6 +208,24 @@ type BCD struct {
 	SubWorld  LevelArea
 }
 
+// Level with the defaults the editor uses for a new course, as far as they're known
+func newEmptyBCD() *BCD {
+	s := &BCD{}
+	s.Header.TimeLimit = 300
+	s.Header.GameStyle = STYLE_M1
+	s.Header.GameVersion = uint32(V3_0_1)
+	s.Header.ManagementFlags = 1
+	s.Header.ClearCheckTime = 0xFFFFFFFF
+	for _, area := range []*LevelArea{&s.OverWorld, &s.SubWorld} {
+		// 240 tiles wide and 27 tall, 16 pixels per tile
+		area.BoundaryRight = 240 * 16
+		area.BoundaryTop = 27 * 16
+	}
+	s.OverWorld.Theme = uint8(OVERWORLD)
+	s.SubWorld.Theme = uint8(UNDERGROUND)
+	return s
+}
+
 func LoadBCD(buf []byte) (*BCD, error) {
 	s := &BCD{}
 	err := s.Load(buf)
@@ -215,35 +233,49 @@ func LoadBCD(buf []byte) (*BCD, error) {
 }
 
 func (s *BCD) Load(buf []byte) error {
-	var err error
-	buf, err = DecryptLevel(buf)
+	if len(buf) != 0x5c000 {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), 0x5c000)
+	}
+	decrypted := levelBufferPool.Get().(*[decryptedLevelSize]byte)
+	defer levelBufferPool.Put(decrypted)
+	err := decryptLevelInto(decrypted[:], buf, &bytes.Buffer{})
 	if err != nil {
 		return err
 	}
-	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, s)
+	decodeBCD(s, decrypted[:])
+	return nil
 }
 
 func (s *BCD) LoadDecrypted(buf []byte) error {
-	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, s)
+	if len(buf) < decryptedLevelSize {
+		return fmt.Errorf("invalid buf size %d < %d", len(buf), decryptedLevelSize)
+	}
+	decodeBCD(s, buf[:decryptedLevelSize])
+	return nil
 }
 
 func (s *BCD) Save() ([]byte, error) {
-	buf := &bytes.Buffer{}
-	err := binary.Write(buf, binary.LittleEndian, s)
+	return s.SaveWithOptions(nil)
+}
+
+func (s *BCD) SaveWithOptions(opts *EncryptOptions) ([]byte, error) {
+	return s.encrypt(opts)
+}
+
+// Encode and encrypt the level in one buffer
+func (s *BCD) encrypt(opts *EncryptOptions) ([]byte, error) {
+	buf := make([]byte, 0x5c000)
+	encodeBCD(buf[0x10:0x5BFD0], s)
+	putBcdHeader(buf)
+	err := encryptLevelInPlace(buf, opts)
 	if err != nil {
-		return []byte{}, err
+		return nil, err
 	}
-	out, err := EncryptLevel(buf.Bytes())
-	return out, err
+	return buf, nil
 }
 
 func (s *BCD) SaveDecrypted() ([]byte, error) {
-	buf := &bytes.Buffer{}
-	err := binary.Write(buf, binary.LittleEndian, s)
-	if err != nil {
-		return []byte{}, err
-	}
-	return buf.Bytes(), err
+	return s.MarshalBinary()
 }
 
 func EncodeToUCS2(str string) []byte {
@@ -275,6 +307,17 @@ func DecodeFromUCS2(src []byte) (string, error) {
 	return string(dst), nil
 }
 
+// Course name up to the first null character
+func (h *Header) CourseName() string {
+	name, _ := decodeUCS2Field(h.Name[:])
+	return name
+}
+
+func (h *Header) CourseDescription() string {
+	description, _ := decodeUCS2Field(h.Description[:])
+	return description
+}
+
 func RemoveUploadedFlag(rawBCD []byte) ([]byte, error) {
 	level, err := LoadBCD(rawBCD)
 	if err != nil {
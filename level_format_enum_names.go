@@ -0,0 +1,642 @@
+// Code generated by gen_enum_names.go from level_format_enums.go. DO NOT EDIT.
+
+package smm2_parsing
+
+var objIdNames = map[ObjId]string{
+	0:   "GOOMBA",
+	1:   "KOOPA",
+	2:   "PIRANHA_FLOWER",
+	3:   "HAMMER_BRO",
+	4:   "BLOCK",
+	5:   "QUESTION_BLOCK",
+	6:   "HARD_BLOCK",
+	7:   "GROUND",
+	8:   "COIN",
+	9:   "PIPE",
+	10:  "SPRING",
+	11:  "LIFT",
+	12:  "THWOMP",
+	13:  "BULLET_BILL_BLASTER",
+	14:  "MUSHROOM_PLATFORM",
+	15:  "BOB_OMB",
+	16:  "SEMISOLID_PLATFORM",
+	17:  "BRIDGE",
+	18:  "P_SWITCH",
+	19:  "POW",
+	20:  "SUPER_MUSHROOM",
+	21:  "DONUT_BLOCK",
+	22:  "CLOUD",
+	23:  "NOTE_BLOCK",
+	24:  "FIRE_BAR",
+	25:  "SPINY",
+	26:  "GOAL_GROUND",
+	27:  "GOAL",
+	28:  "BUZZY_BEETLE",
+	29:  "HIDDEN_BLOCK",
+	30:  "LAKITU",
+	31:  "LAKITU_CLOUD",
+	32:  "BANZAI_BILL",
+	33:  "ONE_UP",
+	34:  "FIRE_FLOWER",
+	35:  "SUPER_STAR",
+	36:  "LAVA_LIFT",
+	37:  "STARTING_BRICK",
+	38:  "STARTING_ARROW",
+	39:  "MAGIKOOPA",
+	40:  "SPIKE_TOP",
+	41:  "BOO",
+	42:  "CLOWN_CAR",
+	43:  "SPIKES",
+	44:  "BIG_MUSHROOM",
+	45:  "SHOE_GOOMBA",
+	46:  "DRY_BONES",
+	47:  "CANNON",
+	48:  "BLOOPER",
+	49:  "CASTLE_BRIDGE",
+	50:  "JUMPING_MACHINE",
+	51:  "SKIPSQUEAK",
+	52:  "WIGGLER",
+	53:  "FAST_CONVEYOR_BELT",
+	54:  "BURNER",
+	55:  "DOOR",
+	56:  "CHEEP_CHEEP",
+	57:  "MUNCHER",
+	58:  "ROCKY_WRENCH",
+	59:  "TRACK",
+	60:  "LAVA_BUBBLE",
+	61:  "CHAIN_CHOMP",
+	62:  "BOWSER",
+	63:  "ICE_BLOCK",
+	64:  "VINE",
+	65:  "STINGBY",
+	66:  "ARROW",
+	67:  "ONE_WAY",
+	68:  "SAW",
+	69:  "PLAYER",
+	70:  "BIG_COIN",
+	71:  "HALF_COLLISION_PLATFORM",
+	72:  "KOOPA_CAR",
+	73:  "CINOBIO",
+	74:  "SPIKE_BALL",
+	75:  "STONE",
+	76:  "TWISTER",
+	77:  "BOOM_BOOM",
+	78:  "POKEY",
+	79:  "P_BLOCK",
+	80:  "SPRINT_PLATFORM",
+	81:  "SMB2_MUSHROOM",
+	82:  "DONUT",
+	83:  "SKEWER",
+	84:  "SNAKE_BLOCK",
+	85:  "TRACK_BLOCK",
+	86:  "CHARVAARGH",
+	87:  "SLIGHT_SLOPE",
+	88:  "STEEP_SLOPE",
+	89:  "REEL_CAMERA",
+	90:  "CHECKPOINT_FLAG",
+	91:  "SEESAW",
+	92:  "RED_COIN",
+	93:  "CLEAR_PIPE",
+	94:  "CONVEYOR_BELT",
+	95:  "KEY",
+	96:  "ANT_TROOPER",
+	97:  "WARP_BOX",
+	98:  "BOWSER_JR",
+	99:  "ON_OFF_BLOCK",
+	100: "DOTTED_LINE_BLOCK",
+	101: "WATER_MARKER",
+	102: "MONTY_MOLE",
+	103: "FISH_BONE",
+	104: "ANGRY_SUN",
+	105: "SWINGING_CLAW",
+	106: "TREE",
+	107: "PIRANHA_CREEPER",
+	108: "BLINKING_BLOCK",
+	109: "SOUND_EFFECT",
+	110: "SPIKE_BLOCK",
+	111: "MECHAKOOPA",
+	112: "CRATE",
+	113: "MUSHROOM_TRAMPOLINE",
+	114: "PORKUPUFFER",
+	115: "CINOBIC",
+	116: "SUPER_HAMMER",
+	117: "BULLY",
+	118: "ICICLE",
+	119: "EXCLAMATION_BLOCK",
+	120: "LEMMY",
+	121: "MORTON",
+	122: "LARRY",
+	123: "WENDY",
+	124: "IGGY",
+	125: "ROY",
+	126: "LUDWIG",
+	127: "CANNON_BOX",
+	128: "PROPELLER_BOX",
+	129: "GOOMBA_MASK",
+	130: "BULLET_BILL_MASK",
+	131: "RED_POW_BOX",
+	132: "ON_OFF_TRAMPOLINE",
+}
+
+func (v ObjId) String() string {
+	return enumString(objIdNames, v)
+}
+
+func (v ObjId) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *ObjId) UnmarshalText(text []byte) error {
+	parsed, err := ParseObjId(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseObjId(s string) (ObjId, error) {
+	return enumParse(objIdNames, s)
+}
+
+var clearConIdNames = map[ClearConId]string{
+	0:          "CLEARCON_NONE",
+	137525990:  "REACH_THE_GOAL_WITHOUT_LANDING_AFTER_LEAVING_THE_GROUND",
+	199585683:  "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MECHAKOOPA",
+	272349836:  "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHEEP_CHEEP",
+	375673178:  "REACH_THE_GOAL_WITHOUT_TAKING_DAMAGE",
+	426197923:  "REACH_THE_GOAL_AS_BOOMERANG_MARIO",
+	436833616:  "REACH_THE_GOAL_WHILE_WEARING_A_SHOE",
+	713979835:  "REACH_THE_GOAL_AS_FIRE_MARIO",
+	744927294:  "REACH_THE_GOAL_AS_FROG_MARIO",
+	751004331:  "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LARRY",
+	900050759:  "REACH_THE_GOAL_AS_RACCOON_MARIO",
+	947659466:  "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BLOOPER",
+	976173462:  "REACH_THE_GOAL_AS_PROPELLER_MARIO",
+	994686866:  "REACH_THE_GOAL_WHILE_WEARING_A_PROPELLER_BOX",
+	998904081:  "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPIKE",
+	1008094897: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOOM_BOOM",
+	1051433633: "REACH_THE_GOAL_WHILE_HOLDING_A_KOOPA_SHELL",
+	1061233896: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PORCUPUFFER",
+	1062253843: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHARVAARGH",
+	1079889509: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLET_BILL",
+	1080535886: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BULLY_BULLIES",
+	1151250770: "REACH_THE_GOAL_WHILE_WEARING_A_GOOMBA_MASK",
+	1182464856: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_HOP_CHOPS",
+	1219761531: "REACH_THE_GOAL_WHILE_HOLDING_A_RED_POW_BLOCK_OR_REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_RED_POW_BLOCK",
+	1221661152: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOB_OMB",
+	1259427138: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPINY_SPINIES",
+	1268255615: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_MEOWSER",
+	1279580818: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANT_TROOPER",
+	1283945123: "REACH_THE_GOAL_ON_A_LAKITUS_CLOUD",
+	1344044032: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOO",
+	1425973877: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROY",
+	1429902736: "REACH_THE_GOAL_WHILE_HOLDING_A_TRAMPOLINE",
+	1431944825: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MORTON",
+	1446467058: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FISH_BONE",
+	1510495760: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MONTY_MOLE",
+	1656179347: "REACH_THE_GOAL_AFTER_PICKING_UP_AT_LEAST_ALL_1_UP_MUSHROOM",
+	1665820273: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_HAMMER_BRO",
+	1676924210: "REACH_THE_GOAL_AFTER_HITTING_AT_LEAST_ALL_P_SWITCH_OR_REACH_THE_GOAL_WHILE_HOLDING_A_P_SWITCH",
+	1715960804: "REACH_THE_GOAL_AFTER_ACTIVATING_AT_LEAST_ALL_POW_BLOCK_OR_REACH_THE_GOAL_WHILE_HOLDING_A_POW_BLOCK",
+	1724036958: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ANGRY_SUN",
+	1730095541: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POKEY",
+	1780278293: "REACH_THE_GOAL_AS_SUPERBALL_MARIO",
+	1839897151: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_POM_POM",
+	1969299694: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PEEPA",
+	2035052211: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAKITU",
+	2038503215: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LEMMY",
+	2048033177: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LAVA_BUBBLE",
+	2076496776: "REACH_THE_GOAL_WHILE_WEARING_A_BULLET_BILL_MASK",
+	2089161429: "REACH_THE_GOAL_AS_BIG_MARIO",
+	2111528319: "REACH_THE_GOAL_AS_CAT_MARIO",
+	2131209407: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBA_GALOOMBA",
+	2139645066: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_THWOMP",
+	2259346429: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_IGGY",
+	2549654281: "REACH_THE_GOAL_WHILE_WEARING_A_DRY_BONES_SHELL",
+	2694559007: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SLEDGE_BRO",
+	2746139466: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_ROCKY_WRENCH",
+	2749601092: "REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_50_COIN",
+	2855236681: "REACH_THE_GOAL_AS_FLYING_SQUIRREL_MARIO",
+	3036298571: "REACH_THE_GOAL_AS_BUZZY_MARIO",
+	3074433106: "REACH_THE_GOAL_AS_BUILDER_MARIO",
+	3146932243: "REACH_THE_GOAL_AS_CAPE_MARIO",
+	3174413484: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WENDY",
+	3206222275: "REACH_THE_GOAL_WHILE_WEARING_A_CANNON_BOX",
+	3314955857: "REACH_THE_GOAL_AS_LINK",
+	3342591980: "REACH_THE_GOAL_WHILE_YOU_HAVE_SUPER_STAR_INVINCIBILITY",
+	3346433512: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_GOOMBRAT_GOOMBUD",
+	3348058176: "REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_10_COIN",
+	3353006607: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BUZZY_BEETLE",
+	3392229961: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BOWSER_JR",
+	3437308486: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_KOOPA_TROOPA",
+	3459144213: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_CHAIN_CHOMP",
+	3466227835: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MUNCHER",
+	3481362698: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_WIGGLER",
+	3513732174: "REACH_THE_GOAL_AS_SMB2_MARIO",
+	3649647177: "REACH_THE_GOAL_IN_A_KOOPA_CLOWN_CAR_JUNIOR_CLOWN_CAR",
+	3725246406: "REACH_THE_GOAL_AS_SPINY_MARIO",
+	3730243509: "REACH_THE_GOAL_IN_A_KOOPA_TROOPA_CAR",
+	3748075486: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_PLANT_JUMPING_PIRANHA_PLANT",
+	3797704544: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_DRY_BONES",
+	3824561269: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_STINGBY_STINGBIES",
+	3833342952: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_PIRANHA_CREEPER",
+	3842179831: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_FIRE_PIRANHA_PLANT",
+	3874680510: "REACH_THE_GOAL_AFTER_BREAKING_AT_LEAST_ALL_CRATES",
+	3974581191: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_LUDWIG",
+	3977257962: "REACH_THE_GOAL_AS_SUPER_MARIO",
+	4042480826: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SKIPSQUEAK",
+	4116396131: "REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_COIN",
+	4117878280: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_MAGIKOOPA",
+	4122555074: "REACH_THE_GOAL_AFTER_GRABBING_AT_LEAST_ALL_30_COIN",
+	4153835197: "REACH_THE_GOAL_AS_BALLOON_MARIO",
+	4172105156: "REACH_THE_GOAL_WHILE_WEARING_A_RED_POW_BOX",
+	4209535561: "REACH_THE_GOAL_WHILE_RIDING_YOSHI",
+	4269094462: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_SPIKE_TOP",
+	4293354249: "REACH_THE_GOAL_AFTER_DEFEATING_AT_LEAST_ALL_BANZAI_BILL",
+}
+
+func (v ClearConId) String() string {
+	return enumString(clearConIdNames, v)
+}
+
+func (v ClearConId) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *ClearConId) UnmarshalText(text []byte) error {
+	parsed, err := ParseClearConId(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseClearConId(s string) (ClearConId, error) {
+	return enumParse(clearConIdNames, s)
+}
+
+var clearConCategoryNames = map[ClearConCategory]string{
+	0: "CATEGORY_NONE",
+	1: "CATEGORY_PARTS",
+	2: "CATEGORY_STATUS",
+	3: "CATEGORY_ACTIONS",
+}
+
+func (v ClearConCategory) String() string {
+	return enumString(clearConCategoryNames, v)
+}
+
+func (v ClearConCategory) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *ClearConCategory) UnmarshalText(text []byte) error {
+	parsed, err := ParseClearConCategory(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseClearConCategory(s string) (ClearConCategory, error) {
+	return enumParse(clearConCategoryNames, s)
+}
+
+var gameVersionNames = map[GameVersion]string{
+	0:  "V1_0_0",
+	1:  "V1_0_1",
+	2:  "V1_1_0",
+	3:  "V2_0_0",
+	4:  "V3_0_0",
+	5:  "V3_0_1",
+	33: "VUNKNOWN",
+}
+
+func (v GameVersion) String() string {
+	return enumString(gameVersionNames, v)
+}
+
+func (v GameVersion) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *GameVersion) UnmarshalText(text []byte) error {
+	parsed, err := ParseGameVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseGameVersion(s string) (GameVersion, error) {
+	return enumParse(gameVersionNames, s)
+}
+
+var courseThemeNames = map[CourseTheme]string{
+	0: "OVERWORLD",
+	1: "UNDERGROUND",
+	2: "CASTLE",
+	3: "AIRSHIP",
+	4: "UNDERWATER",
+	5: "GHOST_HOUSE",
+	6: "SNOW",
+	7: "DESERT",
+	8: "SKY",
+	9: "FOREST",
+}
+
+func (v CourseTheme) String() string {
+	return enumString(courseThemeNames, v)
+}
+
+func (v CourseTheme) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *CourseTheme) UnmarshalText(text []byte) error {
+	parsed, err := ParseCourseTheme(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseCourseTheme(s string) (CourseTheme, error) {
+	return enumParse(courseThemeNames, s)
+}
+
+var autoscrollSpeedNames = map[AutoscrollSpeed]string{
+	0: "AUTOSCROLL_X1",
+	1: "AUTOSCROLL_X2",
+	2: "AUTOSCROLL_X3",
+}
+
+func (v AutoscrollSpeed) String() string {
+	return enumString(autoscrollSpeedNames, v)
+}
+
+func (v AutoscrollSpeed) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *AutoscrollSpeed) UnmarshalText(text []byte) error {
+	parsed, err := ParseAutoscrollSpeed(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseAutoscrollSpeed(s string) (AutoscrollSpeed, error) {
+	return enumParse(autoscrollSpeedNames, s)
+}
+
+var autoscrollTypeNames = map[AutoscrollType]string{
+	0: "AUTOSCROLL_NONE",
+	1: "SLOW",
+	2: "NORMAL",
+	3: "FAST",
+	4: "CUSTOM",
+}
+
+func (v AutoscrollType) String() string {
+	return enumString(autoscrollTypeNames, v)
+}
+
+func (v AutoscrollType) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *AutoscrollType) UnmarshalText(text []byte) error {
+	parsed, err := ParseAutoscrollType(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseAutoscrollType(s string) (AutoscrollType, error) {
+	return enumParse(autoscrollTypeNames, s)
+}
+
+var boundaryTypeNames = map[BoundaryType]string{
+	0: "BUILT_ABOVE_LINE",
+	1: "BUILT_BELOW_LINE",
+}
+
+func (v BoundaryType) String() string {
+	return enumString(boundaryTypeNames, v)
+}
+
+func (v BoundaryType) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *BoundaryType) UnmarshalText(text []byte) error {
+	parsed, err := ParseBoundaryType(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseBoundaryType(s string) (BoundaryType, error) {
+	return enumParse(boundaryTypeNames, s)
+}
+
+var orientationTypeNames = map[OrientationType]string{
+	0: "HORIZONTAL",
+	1: "VERTICAL",
+}
+
+func (v OrientationType) String() string {
+	return enumString(orientationTypeNames, v)
+}
+
+func (v OrientationType) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *OrientationType) UnmarshalText(text []byte) error {
+	parsed, err := ParseOrientationType(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseOrientationType(s string) (OrientationType, error) {
+	return enumParse(orientationTypeNames, s)
+}
+
+var liquidTypeNames = map[LiquidType]string{
+	0: "STATIC",
+	1: "RISING_OR_FALLING",
+	2: "RISING_AND_FALLING",
+}
+
+func (v LiquidType) String() string {
+	return enumString(liquidTypeNames, v)
+}
+
+func (v LiquidType) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *LiquidType) UnmarshalText(text []byte) error {
+	parsed, err := ParseLiquidType(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseLiquidType(s string) (LiquidType, error) {
+	return enumParse(liquidTypeNames, s)
+}
+
+var liquidSpeedNames = map[LiquidSpeed]string{
+	0: "NONE",
+	1: "LIQUID_X1",
+	2: "LIQUID_X2",
+	3: "LIQUID_X3",
+}
+
+func (v LiquidSpeed) String() string {
+	return enumString(liquidSpeedNames, v)
+}
+
+func (v LiquidSpeed) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *LiquidSpeed) UnmarshalText(text []byte) error {
+	parsed, err := ParseLiquidSpeed(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseLiquidSpeed(s string) (LiquidSpeed, error) {
+	return enumParse(liquidSpeedNames, s)
+}
+
+var soundIdNames = map[SoundId]string{
+	0:  "SHOCK",
+	1:  "CLATTER",
+	2:  "KICK",
+	3:  "APPLAUSE",
+	4:  "GLORY",
+	5:  "PUNCH",
+	6:  "LAUGHTER",
+	7:  "BABY",
+	8:  "DING_DONG",
+	9:  "BOSS_MUSIC",
+	10: "HEARTBEAT",
+	11: "SCREAM",
+	12: "DRAMA",
+	13: "JUMP",
+	14: "CHEER",
+	15: "DOOM",
+	16: "FIREWORKS",
+	17: "HONK_HONK",
+	18: "BZZT",
+	19: "BONUS_MUSIC",
+	20: "SILENCE",
+	21: "UNKNOWN1",
+	22: "UNKNOWN2",
+	23: "PARTY_POPPERINGS",
+	24: "BOOO",
+	25: "GUFFAW",
+	26: "NEAR_MISS",
+	27: "UNKNOWN3",
+	28: "UNKNOWN4",
+	29: "OINK",
+	30: "KUH_THUNK",
+	31: "BEEP",
+	32: "NINJA_ATTACKGERS",
+	33: "UNKNOWN5",
+	34: "UNKNOWN6",
+	35: "ZAP",
+	36: "FLASH",
+	37: "YEAH",
+	38: "AWW",
+	39: "UNKNOWN7",
+	40: "UNKNOWN8",
+	41: "AUDIENCE",
+	42: "SCATTING",
+	43: "SPARK",
+	44: "TRADITIONAL",
+	45: "ELECTRIC_GUITAR",
+	46: "TWISTY_TURNY",
+	47: "WOOZY",
+	48: "FINAL_BOSS",
+	49: "PEACEFUL",
+	50: "HORROR",
+	51: "SUPER_MARIO_GALAXY",
+	52: "SUPER_MARIO_64",
+	53: "SUPER_MARIO_SUNSHINE",
+	54: "SUPER_MARIO_KART",
+	55: "UNKNOWN9",
+}
+
+func (v SoundId) String() string {
+	return enumString(soundIdNames, v)
+}
+
+func (v SoundId) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *SoundId) UnmarshalText(text []byte) error {
+	parsed, err := ParseSoundId(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseSoundId(s string) (SoundId, error) {
+	return enumParse(soundIdNames, s)
+}
+
+var pathDirectionNames = map[PathDirection]string{
+	1: "DIRECTION_RIGHT",
+	2: "DIRECTION_LEFT",
+	3: "DIRECTION_DOWN",
+	4: "DIRECTION_UP",
+}
+
+func (v PathDirection) String() string {
+	return enumString(pathDirectionNames, v)
+}
+
+func (v PathDirection) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *PathDirection) UnmarshalText(text []byte) error {
+	parsed, err := ParsePathDirection(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParsePathDirection(s string) (PathDirection, error) {
+	return enumParse(pathDirectionNames, s)
+}
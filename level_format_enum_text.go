@@ -0,0 +1,70 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Helpers for the name tables in level_format_enum_names.go, which is
+// generated from level_format_enums.go. Values without a name are printed and
+// parsed as plain decimal numbers so nothing is lost.
+
+type enumValue interface {
+	~uint8 | ~uint16 | ~uint32
+}
+
+func enumString[T enumValue](names map[T]string, v T) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func enumParse[T enumValue](names map[T]string, s string) (T, error) {
+	for v, name := range names {
+		if name == s {
+			return v, nil
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || uint64(T(n)) != n {
+		var zero T
+		return zero, fmt.Errorf("unknown %T name %q", zero, s)
+	}
+	return T(n), nil
+}
+
+// Unknown styles that aren't printable are written as 0x followed by the two
+// bytes in hex so they survive a round trip
+func (v GameStyle) String() string {
+	if v[0] >= 0x20 && v[0] < 0x7F && v[1] >= 0x20 && v[1] < 0x7F {
+		return string(v[:])
+	}
+	return fmt.Sprintf("0x%02x%02x", v[0], v[1])
+}
+
+func (v GameStyle) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *GameStyle) UnmarshalText(text []byte) error {
+	parsed, err := ParseGameStyle(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func ParseGameStyle(s string) (GameStyle, error) {
+	if len(s) == 2 {
+		return GameStyle{s[0], s[1]}, nil
+	}
+	if len(s) == 6 && s[:2] == "0x" {
+		n, err := strconv.ParseUint(s[2:], 16, 16)
+		if err == nil {
+			return GameStyle{byte(n >> 8), byte(n)}, nil
+		}
+	}
+	return GameStyle{}, fmt.Errorf("unknown GameStyle name %q", s)
+}
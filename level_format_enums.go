@@ -3,6 +3,8 @@ package smm2_parsing
 // Some of these values come from https://github.com/TheGreatRambler/toost/blob/main/src/LevelParser.hpp
 // and others come from https://github.com/liamadvance/smm2-documentation/blob/master/Course%20Format.md
 
+//go:generate go run gen_enum_names.go
+
 type ObjId uint16
 
 const (
@@ -141,6 +143,16 @@ const (
 	ON_OFF_TRAMPOLINE       ObjId = 132
 )
 
+// Object.X and Object.Y are stored in these units, a tile is 160
+const OBJECT_TILE_UNITS = 160
+
+// Object.Flag bits
+const (
+	OBJFLAG_WINGS     uint32 = 0x2
+	OBJFLAG_BIG       uint32 = 0x4000
+	OBJFLAG_PARACHUTE uint32 = 0x8000
+)
+
 type ClearConId uint32
 
 const (
@@ -261,6 +273,17 @@ const (
 	VUNKNOWN GameVersion = 33
 )
 
+// Stored as two ASCII characters, "M1" for Super Mario Bros. and so on
+type GameStyle [2]byte
+
+var (
+	STYLE_M1 = GameStyle{'M', '1'}
+	STYLE_M3 = GameStyle{'M', '3'}
+	STYLE_MW = GameStyle{'M', 'W'}
+	STYLE_WU = GameStyle{'W', 'U'}
+	STYLE_3W = GameStyle{'3', 'W'}
+)
+
 type CourseTheme uint8
 
 const (
@@ -297,8 +320,10 @@ const (
 type BoundaryType uint8
 
 const (
+	// Values from the boundary_type enum of the community level format
+	// description (toost's level.ksy), not checked against a level dump here
 	BUILT_ABOVE_LINE BoundaryType = 0
-	BUILT_BELOW_LINE BoundaryType = 0
+	BUILT_BELOW_LINE BoundaryType = 1
 )
 
 type OrientationType uint8
@@ -385,3 +410,14 @@ const (
 	SUPER_MARIO_KART     SoundId = 54
 	UNKNOWN9             SoundId = 55
 )
+
+// Direction of a node in a snake block, clear pipe, piranha creeper, ! block or
+// track block path
+type PathDirection uint8
+
+const (
+	DIRECTION_RIGHT PathDirection = 1
+	DIRECTION_LEFT  PathDirection = 2
+	DIRECTION_DOWN  PathDirection = 3
+	DIRECTION_UP    PathDirection = 4
+)
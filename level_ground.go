@@ -0,0 +1,247 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Solid ground cells of an area, indexed by tile. Y goes up like in levels.
+type GroundGrid struct {
+	Width  int
+	Height int
+	cells  []bool
+}
+
+func NewGroundGrid(width int, height int) *GroundGrid {
+	return &GroundGrid{Width: width, Height: height, cells: make([]bool, width*height)}
+}
+
+// Grid of the area's ground, as large as its boundaries or the furthest cell
+func (a *LevelArea) GroundGrid() (*GroundGrid, error) {
+	if int(a.GroundCount) > len(a.Ground) {
+		return nil, fmt.Errorf("ground count %d > %d", a.GroundCount, len(a.Ground))
+	}
+	width := int(a.BoundaryRight) / 16
+	height := int(a.BoundaryTop) / 16
+	// Ground positions are bytes
+	if width > 256 {
+		width = 256
+	}
+	if height > 256 {
+		height = 256
+	}
+	for _, g := range a.Ground[:a.GroundCount] {
+		if int(g.X) >= width {
+			width = int(g.X) + 1
+		}
+		if int(g.Y) >= height {
+			height = int(g.Y) + 1
+		}
+	}
+
+	grid := NewGroundGrid(width, height)
+	for _, g := range a.Ground[:a.GroundCount] {
+		grid.Set(int(g.X), int(g.Y), true)
+	}
+	return grid, nil
+}
+
+// Cells outside the grid aren't solid
+func (g *GroundGrid) Solid(x int, y int) bool {
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return false
+	}
+	return g.cells[y*g.Width+x]
+}
+
+// Cells outside the grid are ignored
+func (g *GroundGrid) Set(x int, y int, solid bool) {
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return
+	}
+	g.cells[y*g.Width+x] = solid
+}
+
+func (g *GroundGrid) SetRect(r image.Rectangle, solid bool) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			g.Set(x, y, solid)
+		}
+	}
+}
+
+// Solid cells from the bottom row up, left to right
+func (g *GroundGrid) Cells() []image.Point {
+	var cells []image.Point
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			if g.Solid(x, y) {
+				cells = append(cells, image.Point{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+// Groups of solid cells connected through their edges, in the order of Cells
+func (g *GroundGrid) Components() [][]image.Point {
+	seen := make([]bool, len(g.cells))
+	var components [][]image.Point
+	for _, start := range g.Cells() {
+		if seen[start.Y*g.Width+start.X] {
+			continue
+		}
+		seen[start.Y*g.Width+start.X] = true
+		component := []image.Point{start}
+		for i := 0; i < len(component); i++ {
+			c := component[i]
+			for _, n := range []image.Point{{c.X + 1, c.Y}, {c.X - 1, c.Y}, {c.X, c.Y + 1}, {c.X, c.Y - 1}} {
+				if g.Solid(n.X, n.Y) && !seen[n.Y*g.Width+n.X] {
+					seen[n.Y*g.Width+n.X] = true
+					component = append(component, n)
+				}
+			}
+		}
+		sort.Slice(component, func(i, j int) bool {
+			if component[i].Y != component[j].Y {
+				return component[i].Y < component[j].Y
+			}
+			return component[i].X < component[j].X
+		})
+		components = append(components, component)
+	}
+	return components
+}
+
+// Bits of the solid neighbours of a cell
+const (
+	GROUND_UP         uint8 = 1 << 0
+	GROUND_UP_RIGHT   uint8 = 1 << 1
+	GROUND_RIGHT      uint8 = 1 << 2
+	GROUND_DOWN_RIGHT uint8 = 1 << 3
+	GROUND_DOWN       uint8 = 1 << 4
+	GROUND_DOWN_LEFT  uint8 = 1 << 5
+	GROUND_LEFT       uint8 = 1 << 6
+	GROUND_UP_LEFT    uint8 = 1 << 7
+)
+
+// Solid neighbours of a cell. A corner only counts when both edges next to
+// it are solid too, other corners don't change how a tile looks.
+func (g *GroundGrid) Neighbours(x int, y int) uint8 {
+	var mask uint8
+	bit := func(dx int, dy int, b uint8) {
+		if g.Solid(x+dx, y+dy) {
+			mask |= b
+		}
+	}
+	bit(0, 1, GROUND_UP)
+	bit(1, 0, GROUND_RIGHT)
+	bit(0, -1, GROUND_DOWN)
+	bit(-1, 0, GROUND_LEFT)
+	corner := func(dx int, dy int, b uint8, edges uint8) {
+		if mask&edges == edges {
+			bit(dx, dy, b)
+		}
+	}
+	corner(1, 1, GROUND_UP_RIGHT, GROUND_UP|GROUND_RIGHT)
+	corner(1, -1, GROUND_DOWN_RIGHT, GROUND_DOWN|GROUND_RIGHT)
+	corner(-1, -1, GROUND_DOWN_LEFT, GROUND_DOWN|GROUND_LEFT)
+	corner(-1, 1, GROUND_UP_LEFT, GROUND_UP|GROUND_LEFT)
+	return mask
+}
+
+// Ground.Id for each neighbour mask. The game's table isn't documented, so it
+// is learned from levels made in the game with LearnGroundTileset.
+type GroundTileset map[uint8]uint8
+
+// The most common Id for each neighbour mask in the given areas
+func LearnGroundTileset(areas ...*LevelArea) (GroundTileset, error) {
+	counts := map[uint8]map[uint8]int{}
+	for i, a := range areas {
+		grid, err := a.GroundGrid()
+		if err != nil {
+			return nil, fmt.Errorf("area %d: %v", i, err)
+		}
+		for _, g := range a.Ground[:a.GroundCount] {
+			mask := grid.Neighbours(int(g.X), int(g.Y))
+			if counts[mask] == nil {
+				counts[mask] = map[uint8]int{}
+			}
+			counts[mask][g.Id]++
+		}
+	}
+
+	tileset := GroundTileset{}
+	for mask, ids := range counts {
+		best := -1
+		for id, count := range ids {
+			// Lowest Id on ties so the result doesn't depend on map order
+			if count > best || (count == best && id < tileset[mask]) {
+				tileset[mask] = id
+				best = count
+			}
+		}
+	}
+	return tileset, nil
+}
+
+// Set the Id of every ground entry from its neighbours. Entries whose mask
+// isn't in the tileset are left unchanged and reported in the error.
+func (a *LevelArea) AutoTile(tileset GroundTileset) error {
+	grid, err := a.GroundGrid()
+	if err != nil {
+		return err
+	}
+	missing := map[uint8]int{}
+	for i := range a.Ground[:a.GroundCount] {
+		g := &a.Ground[i]
+		mask := grid.Neighbours(int(g.X), int(g.Y))
+		id, ok := tileset[mask]
+		if !ok {
+			missing[mask]++
+			continue
+		}
+		g.Id = id
+	}
+	if len(missing) != 0 {
+		cells := 0
+		var masks []int
+		for mask, count := range missing {
+			cells += count
+			masks = append(masks, int(mask))
+		}
+		sort.Ints(masks)
+		return fmt.Errorf("%d ground cells have neighbour masks missing from the tileset: %v", cells, masks)
+	}
+	return nil
+}
+
+// Replace the area's ground with the grid's solid cells and tile them. Ground
+// already in the area keeps its BackgroundId.
+func (a *LevelArea) SetGround(grid *GroundGrid, tileset GroundTileset) error {
+	cells := grid.Cells()
+	if len(cells) > len(a.Ground) {
+		return fmt.Errorf("%d ground cells > %d", len(cells), len(a.Ground))
+	}
+	if grid.Width > 256 || grid.Height > 256 {
+		return fmt.Errorf("ground grid %dx%d larger than 256x256", grid.Width, grid.Height)
+	}
+	if int(a.GroundCount) > len(a.Ground) {
+		return fmt.Errorf("ground count %d > %d", a.GroundCount, len(a.Ground))
+	}
+
+	backgrounds := map[image.Point]uint8{}
+	for _, g := range a.Ground[:a.GroundCount] {
+		backgrounds[image.Point{int(g.X), int(g.Y)}] = g.BackgroundId
+	}
+	a.Ground = [len(a.Ground)]Ground{}
+	for i, c := range cells {
+		a.Ground[i] = Ground{X: uint8(c.X), Y: uint8(c.Y), BackgroundId: backgrounds[c]}
+	}
+	a.GroundCount = uint32(len(cells))
+	if tileset == nil {
+		return nil
+	}
+	return a.AutoTile(tileset)
+}
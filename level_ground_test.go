@@ -0,0 +1,86 @@
+package smm2_parsing
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroundNeighbours(t *testing.T) {
+	g := NewGroundGrid(5, 5)
+	g.SetRect(image.Rect(1, 1, 4, 4), true)
+	g.Set(0, 4, true)
+
+	for _, test := range []struct {
+		x, y int
+		mask uint8
+	}{
+		// Middle of a 3x3 block has every neighbour
+		{2, 2, 0xFF},
+		{1, 1, GROUND_UP | GROUND_UP_RIGHT | GROUND_RIGHT},
+		{3, 3, GROUND_DOWN | GROUND_DOWN_LEFT | GROUND_LEFT},
+		// 0,4 touches 1,3 by its corner only, which doesn't count
+		{1, 3, GROUND_RIGHT | GROUND_DOWN_RIGHT | GROUND_DOWN},
+		{0, 4, 0},
+		// Cells outside the grid aren't solid
+		{4, 2, GROUND_LEFT},
+	} {
+		if mask := g.Neighbours(test.x, test.y); mask != test.mask {
+			t.Errorf("Neighbours(%d, %d) = %08b, want %08b", test.x, test.y, mask, test.mask)
+		}
+	}
+}
+
+func TestGroundComponents(t *testing.T) {
+	g := NewGroundGrid(6, 4)
+	g.SetRect(image.Rect(0, 0, 2, 1), true)
+	g.Set(1, 1, true)
+	// Only touches the first group by a corner
+	g.Set(2, 2, true)
+	g.SetRect(image.Rect(4, 0, 5, 3), true)
+
+	want := [][]image.Point{
+		{{0, 0}, {1, 0}, {1, 1}},
+		{{4, 0}, {4, 1}, {4, 2}},
+		{{2, 2}},
+	}
+	if components := g.Components(); !reflect.DeepEqual(components, want) {
+		t.Errorf("components %v, want %v", components, want)
+	}
+}
+
+func TestAutoTile(t *testing.T) {
+	grid := NewGroundGrid(240, 27)
+	grid.SetRect(image.Rect(0, 0, 20, 3), true)
+	grid.SetRect(image.Rect(30, 5, 34, 6), true)
+
+	// Ids from a made up rule, the game's are learned the same way
+	source := &newEmptyBCD().OverWorld
+	if err := source.SetGround(grid, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := range source.Ground[:source.GroundCount] {
+		g := &source.Ground[i]
+		g.Id = grid.Neighbours(int(g.X), int(g.Y))
+	}
+	tileset, err := LearnGroundTileset(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	area := &newEmptyBCD().OverWorld
+	if err := area.SetGround(grid, tileset); err != nil {
+		t.Fatal(err)
+	}
+	if area.Ground != source.Ground {
+		t.Errorf("AutoTile didn't reproduce the learned Ids")
+	}
+
+	// A lone cell has a mask the tileset hasn't seen
+	grid.Set(100, 10, true)
+	err = area.SetGround(grid, tileset)
+	if err == nil || !strings.Contains(err.Error(), "1 ground cells have neighbour masks missing from the tileset: [0]") {
+		t.Errorf("missing mask error %v", err)
+	}
+}
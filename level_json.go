@@ -0,0 +1,626 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON representation of a BCD. Only the counted entries of each array are
+// written, enums use their names and the name and description are plain
+// strings. Anything that can't be represented that way (unknown blocks, stale
+// entries past the counts, names that don't re-encode identically) is kept as
+// base64 so SaveDecrypted stays byte identical after a round trip.
+
+type jsonHeader struct {
+	YStart                  uint8
+	YGoal                   uint8
+	XGoal                   uint16
+	TimeLimit               uint16
+	ClearConditionMagnitude uint16
+	CreationYear            uint16
+	CreationMonth           uint8
+	CreationDay             uint8
+	CreationHour            uint8
+	CreationMinute          uint8
+	AutoscrollSpeed         AutoscrollSpeed
+	ClearConditionCategory  ClearConCategory
+	ClearConditionObject    ClearConId
+	UnkGameVer              uint32
+	ManagementFlags         uint32
+	ClearAttemmpts          uint32
+	ClearCheckTime          uint32
+	CreationId              uint32
+	UploadId                uint64
+	GameVersion             GameVersion
+	Unk1                    []byte
+	GameStyle               GameStyle
+	Unk2                    uint8
+	Name                    string
+	NameRaw                 []byte `json:",omitempty"`
+	Description             string
+	DescriptionRaw          []byte `json:",omitempty"`
+}
+
+type jsonObject struct {
+	X      uint32
+	Y      uint32
+	Unk1   uint16
+	Width  uint8
+	Height uint8
+	Flag   uint32
+	CFlag  uint32
+	Ex     uint32
+	Id     ObjId
+	CId    ObjId
+	LId    uint16
+	SId    uint16
+}
+
+type jsonSound struct {
+	Id   SoundId
+	X    uint8
+	Y    uint8
+	Unk1 uint8
+}
+
+type jsonSnake struct {
+	Index         uint8
+	Unk1          uint16
+	Nodes         []SnakeNode
+	NodesResidual []byte `json:",omitempty"`
+}
+
+type jsonClearPipe struct {
+	Index         uint8
+	Unk           uint16
+	Nodes         []ClearPipeNode
+	NodesResidual []byte `json:",omitempty"`
+}
+
+type jsonPiranhaCreeper struct {
+	Unk1          uint8
+	Index         uint8
+	Unk2          uint8
+	Nodes         []PiranhaCreeperNode
+	NodesResidual []byte `json:",omitempty"`
+}
+
+type jsonExclamationBlock struct {
+	Unk1          uint8
+	Index         uint8
+	Unk2          uint8
+	Nodes         []ExclamationBlockNode
+	NodesResidual []byte `json:",omitempty"`
+}
+
+type jsonTrackBlock struct {
+	Unk1          uint8
+	Index         uint8
+	Unk2          uint8
+	Nodes         []TrackBlockNode
+	NodesResidual []byte `json:",omitempty"`
+}
+
+type jsonLevelArea struct {
+	Theme             CourseTheme
+	AutoscrollType    AutoscrollType
+	BoundaryType      BoundaryType
+	Orientation       OrientationType
+	LiquidEndHeight   uint8
+	LiquidType        LiquidType
+	LiquidSpeed       LiquidSpeed
+	LiquidStartHeight uint8
+	BoundaryRight     uint32
+	BoundaryTop       uint32
+	BoundaryLeft      uint32
+	BoundaryBottom    uint32
+	UnkFlag           uint32
+	Unk1              uint32
+	Objects           []jsonObject
+	Sounds            []jsonSound
+	Snakes            []jsonSnake
+	ClearPipes        []jsonClearPipe
+	PiranhaCreepers   []jsonPiranhaCreeper
+	ExclamationBlocks []jsonExclamationBlock
+	TrackBlocks       []jsonTrackBlock
+	Ground            []Ground
+	Tracks            []Track
+	Icicles           []Icicle
+	Unk2              []byte
+	// Entries past the counts that aren't zeroed, keyed by array name
+	Residual map[string][]byte `json:",omitempty"`
+}
+
+type jsonBCD struct {
+	Header    jsonHeader
+	OverWorld jsonLevelArea
+	SubWorld  jsonLevelArea
+}
+
+// Pointer receiver like the other BCD methods, a BCD is too large to copy on
+// every call. Marshal a *BCD, BCD values that aren't addressable (like map
+// values) are written field by field by encoding/json instead.
+func (s *BCD) MarshalJSON() ([]byte, error) {
+	doc := &jsonBCD{}
+	doc.Header.fromHeader(&s.Header)
+	err := doc.OverWorld.fromLevelArea(&s.OverWorld)
+	if err != nil {
+		return nil, fmt.Errorf("overworld: %v", err)
+	}
+	err = doc.SubWorld.fromLevelArea(&s.SubWorld)
+	if err != nil {
+		return nil, fmt.Errorf("subworld: %v", err)
+	}
+	return json.Marshal(doc)
+}
+
+func (s *BCD) UnmarshalJSON(buf []byte) error {
+	doc := &jsonBCD{}
+	err := json.Unmarshal(buf, doc)
+	if err != nil {
+		return err
+	}
+
+	*s = BCD{}
+	err = doc.Header.toHeader(&s.Header)
+	if err != nil {
+		return err
+	}
+	err = doc.OverWorld.toLevelArea(&s.OverWorld)
+	if err != nil {
+		return fmt.Errorf("overworld: %v", err)
+	}
+	err = doc.SubWorld.toLevelArea(&s.SubWorld)
+	if err != nil {
+		return fmt.Errorf("subworld: %v", err)
+	}
+	return nil
+}
+
+func (d *jsonHeader) fromHeader(h *Header) {
+	*d = jsonHeader{
+		YStart:                  h.YStart,
+		YGoal:                   h.YGoal,
+		XGoal:                   h.XGoal,
+		TimeLimit:               h.TimeLimit,
+		ClearConditionMagnitude: h.ClearConditionMagnitude,
+		CreationYear:            h.CreationYear,
+		CreationMonth:           h.CreationMonth,
+		CreationDay:             h.CreationDay,
+		CreationHour:            h.CreationHour,
+		CreationMinute:          h.CreationMinute,
+		AutoscrollSpeed:         AutoscrollSpeed(h.AutoscrollSpeed),
+		ClearConditionCategory:  ClearConCategory(h.ClearConditionCategory),
+		ClearConditionObject:    ClearConId(h.ClearConditionObject),
+		UnkGameVer:              h.UnkGameVer,
+		ManagementFlags:         h.ManagementFlags,
+		ClearAttemmpts:          h.ClearAttemmpts,
+		ClearCheckTime:          h.ClearCheckTime,
+		CreationId:              h.CreationId,
+		UploadId:                h.UploadId,
+		GameVersion:             GameVersion(h.GameVersion),
+		Unk1:                    bytes.TrimRight(h.Unk1[:], "\x00"),
+		GameStyle:               h.GameStyle,
+		Unk2:                    h.Unk2,
+	}
+
+	var exact bool
+	d.Name, exact = decodeUCS2Field(h.Name[:])
+	if !exact {
+		d.NameRaw = h.Name[:]
+	}
+	d.Description, exact = decodeUCS2Field(h.Description[:])
+	if !exact {
+		d.DescriptionRaw = h.Description[:]
+	}
+}
+
+func (d *jsonHeader) toHeader(h *Header) error {
+	*h = Header{
+		YStart:                  d.YStart,
+		YGoal:                   d.YGoal,
+		XGoal:                   d.XGoal,
+		TimeLimit:               d.TimeLimit,
+		ClearConditionMagnitude: d.ClearConditionMagnitude,
+		CreationYear:            d.CreationYear,
+		CreationMonth:           d.CreationMonth,
+		CreationDay:             d.CreationDay,
+		CreationHour:            d.CreationHour,
+		CreationMinute:          d.CreationMinute,
+		AutoscrollSpeed:         uint8(d.AutoscrollSpeed),
+		ClearConditionCategory:  uint8(d.ClearConditionCategory),
+		ClearConditionObject:    uint32(d.ClearConditionObject),
+		UnkGameVer:              d.UnkGameVer,
+		ManagementFlags:         d.ManagementFlags,
+		ClearAttemmpts:          d.ClearAttemmpts,
+		ClearCheckTime:          d.ClearCheckTime,
+		CreationId:              d.CreationId,
+		UploadId:                d.UploadId,
+		GameVersion:             uint32(d.GameVersion),
+		GameStyle:               d.GameStyle,
+		Unk2:                    d.Unk2,
+	}
+
+	err := copyFixed(h.Unk1[:], d.Unk1, "Unk1")
+	if err != nil {
+		return err
+	}
+
+	if d.NameRaw != nil {
+		err = copyFixed(h.Name[:], d.NameRaw, "NameRaw")
+	} else {
+		err = encodeUCS2Field(h.Name[:], d.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("name: %v", err)
+	}
+
+	if d.DescriptionRaw != nil {
+		err = copyFixed(h.Description[:], d.DescriptionRaw, "DescriptionRaw")
+	} else {
+		err = encodeUCS2Field(h.Description[:], d.Description)
+	}
+	if err != nil {
+		return fmt.Errorf("description: %v", err)
+	}
+
+	return nil
+}
+
+func (d *jsonLevelArea) fromLevelArea(a *LevelArea) error {
+	*d = jsonLevelArea{
+		Theme:             CourseTheme(a.Theme),
+		AutoscrollType:    AutoscrollType(a.AutoscrollType),
+		BoundaryType:      BoundaryType(a.BoundaryType),
+		Orientation:       OrientationType(a.Orientation),
+		LiquidEndHeight:   a.LiquidEndHeight,
+		LiquidType:        LiquidType(a.LiquidType),
+		LiquidSpeed:       LiquidSpeed(a.LiquidSpeed),
+		LiquidStartHeight: a.LiquidStartHeight,
+		BoundaryRight:     a.BoundaryRight,
+		BoundaryTop:       a.BoundaryTop,
+		BoundaryLeft:      a.BoundaryLeft,
+		BoundaryBottom:    a.BoundaryBottom,
+		UnkFlag:           a.UnkFlag,
+		Unk1:              a.Unk1,
+		Unk2:              bytes.TrimRight(a.Unk2[:], "\x00"),
+		Residual:          map[string][]byte{},
+	}
+
+	for _, c := range a.counts() {
+		if int(*c.count) > c.capacity {
+			return fmt.Errorf("%s count %d > %d", c.name, *c.count, c.capacity)
+		}
+	}
+
+	d.Objects = make([]jsonObject, a.ObjectCount)
+	for i, o := range a.Objects[:a.ObjectCount] {
+		d.Objects[i] = jsonObject{o.X, o.Y, o.Unk1, o.Width, o.Height, o.Flag, o.CFlag, o.Ex, ObjId(o.Id), ObjId(o.CId), o.LId, o.SId}
+	}
+
+	d.Sounds = make([]jsonSound, a.SoundEffectCount)
+	for i, o := range a.Sounds[:a.SoundEffectCount] {
+		d.Sounds[i] = jsonSound{SoundId(o.Id), o.X, o.Y, o.Unk1}
+	}
+
+	var err error
+	d.Snakes = make([]jsonSnake, a.SnakeBlockCount)
+	for i, o := range a.Snakes[:a.SnakeBlockCount] {
+		d.Snakes[i] = jsonSnake{Index: o.Index, Unk1: o.Unk1}
+		d.Snakes[i].Nodes, d.Snakes[i].NodesResidual, err = splitNodes(o.Nodes[:], int(o.NodeCount))
+		if err != nil {
+			return fmt.Errorf("snake %d: %v", i, err)
+		}
+	}
+
+	d.ClearPipes = make([]jsonClearPipe, a.ClearPipeCount)
+	for i, o := range a.ClearPipes[:a.ClearPipeCount] {
+		d.ClearPipes[i] = jsonClearPipe{Index: o.Index, Unk: o.Unk}
+		d.ClearPipes[i].Nodes, d.ClearPipes[i].NodesResidual, err = splitNodes(o.Nodes[:], int(o.NodeCount))
+		if err != nil {
+			return fmt.Errorf("clear pipe %d: %v", i, err)
+		}
+	}
+
+	d.PiranhaCreepers = make([]jsonPiranhaCreeper, a.PiranhaCreeperCount)
+	for i, o := range a.PiranhaCreepers[:a.PiranhaCreeperCount] {
+		d.PiranhaCreepers[i] = jsonPiranhaCreeper{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		d.PiranhaCreepers[i].Nodes, d.PiranhaCreepers[i].NodesResidual, err = splitNodes(o.Nodes[:], int(o.NodeCount))
+		if err != nil {
+			return fmt.Errorf("piranha creeper %d: %v", i, err)
+		}
+	}
+
+	d.ExclamationBlocks = make([]jsonExclamationBlock, a.ExclamationMarkBlockCount)
+	for i, o := range a.ExclamationBlocks[:a.ExclamationMarkBlockCount] {
+		d.ExclamationBlocks[i] = jsonExclamationBlock{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		d.ExclamationBlocks[i].Nodes, d.ExclamationBlocks[i].NodesResidual, err = splitNodes(o.Nodes[:], int(o.NodeCount))
+		if err != nil {
+			return fmt.Errorf("exclamation block %d: %v", i, err)
+		}
+	}
+
+	d.TrackBlocks = make([]jsonTrackBlock, a.TrackBlockCount)
+	for i, o := range a.TrackBlocks[:a.TrackBlockCount] {
+		d.TrackBlocks[i] = jsonTrackBlock{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		d.TrackBlocks[i].Nodes, d.TrackBlocks[i].NodesResidual, err = splitNodes(o.Nodes[:], int(o.NodeCount))
+		if err != nil {
+			return fmt.Errorf("track block %d: %v", i, err)
+		}
+	}
+
+	d.Ground = append([]Ground{}, a.Ground[:a.GroundCount]...)
+	d.Tracks = append([]Track{}, a.Tracks[:a.TrackCount]...)
+	d.Icicles = append([]Icicle{}, a.Icicles[:a.IceCount]...)
+
+	for _, r := range a.residuals() {
+		raw, err := r.get()
+		if err != nil {
+			return fmt.Errorf("%s: %v", r.name, err)
+		}
+		if raw != nil {
+			d.Residual[r.name] = raw
+		}
+	}
+
+	return nil
+}
+
+func (d *jsonLevelArea) toLevelArea(a *LevelArea) error {
+	*a = LevelArea{
+		Theme:             uint8(d.Theme),
+		AutoscrollType:    uint8(d.AutoscrollType),
+		BoundaryType:      uint8(d.BoundaryType),
+		Orientation:       uint8(d.Orientation),
+		LiquidEndHeight:   d.LiquidEndHeight,
+		LiquidType:        uint8(d.LiquidType),
+		LiquidSpeed:       uint8(d.LiquidSpeed),
+		LiquidStartHeight: d.LiquidStartHeight,
+		BoundaryRight:     d.BoundaryRight,
+		BoundaryTop:       d.BoundaryTop,
+		BoundaryLeft:      d.BoundaryLeft,
+		BoundaryBottom:    d.BoundaryBottom,
+		UnkFlag:           d.UnkFlag,
+		Unk1:              d.Unk1,
+	}
+
+	err := copyFixed(a.Unk2[:], d.Unk2, "Unk2")
+	if err != nil {
+		return err
+	}
+
+	lengths := map[string]int{
+		"Objects":           len(d.Objects),
+		"Sounds":            len(d.Sounds),
+		"Snakes":            len(d.Snakes),
+		"ClearPipes":        len(d.ClearPipes),
+		"PiranhaCreepers":   len(d.PiranhaCreepers),
+		"ExclamationBlocks": len(d.ExclamationBlocks),
+		"TrackBlocks":       len(d.TrackBlocks),
+		"Ground":            len(d.Ground),
+		"Tracks":            len(d.Tracks),
+		"Icicles":           len(d.Icicles),
+	}
+	for _, c := range a.counts() {
+		if lengths[c.name] > c.capacity {
+			return fmt.Errorf("too many %s %d > %d", c.name, lengths[c.name], c.capacity)
+		}
+		*c.count = uint32(lengths[c.name])
+	}
+
+	for i, o := range d.Objects {
+		a.Objects[i] = Object{o.X, o.Y, o.Unk1, o.Width, o.Height, o.Flag, o.CFlag, o.Ex, uint16(o.Id), uint16(o.CId), o.LId, o.SId}
+	}
+
+	for i, o := range d.Sounds {
+		a.Sounds[i] = Sound{uint8(o.Id), o.X, o.Y, o.Unk1}
+	}
+
+	for i, o := range d.Snakes {
+		a.Snakes[i] = Snake{Index: o.Index, Unk1: o.Unk1}
+		a.Snakes[i].NodeCount, err = joinNodes(a.Snakes[i].Nodes[:], o.Nodes, o.NodesResidual)
+		if err != nil {
+			return fmt.Errorf("snake %d: %v", i, err)
+		}
+	}
+
+	for i, o := range d.ClearPipes {
+		a.ClearPipes[i] = ClearPipe{Index: o.Index, Unk: o.Unk}
+		a.ClearPipes[i].NodeCount, err = joinNodes(a.ClearPipes[i].Nodes[:], o.Nodes, o.NodesResidual)
+		if err != nil {
+			return fmt.Errorf("clear pipe %d: %v", i, err)
+		}
+	}
+
+	for i, o := range d.PiranhaCreepers {
+		a.PiranhaCreepers[i] = PiranhaCreeper{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		a.PiranhaCreepers[i].NodeCount, err = joinNodes(a.PiranhaCreepers[i].Nodes[:], o.Nodes, o.NodesResidual)
+		if err != nil {
+			return fmt.Errorf("piranha creeper %d: %v", i, err)
+		}
+	}
+
+	for i, o := range d.ExclamationBlocks {
+		a.ExclamationBlocks[i] = ExclamationBlock{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		a.ExclamationBlocks[i].NodeCount, err = joinNodes(a.ExclamationBlocks[i].Nodes[:], o.Nodes, o.NodesResidual)
+		if err != nil {
+			return fmt.Errorf("exclamation block %d: %v", i, err)
+		}
+	}
+
+	for i, o := range d.TrackBlocks {
+		a.TrackBlocks[i] = TrackBlock{Unk1: o.Unk1, Index: o.Index, Unk2: o.Unk2}
+		a.TrackBlocks[i].NodeCount, err = joinNodes(a.TrackBlocks[i].Nodes[:], o.Nodes, o.NodesResidual)
+		if err != nil {
+			return fmt.Errorf("track block %d: %v", i, err)
+		}
+	}
+
+	copy(a.Ground[:], d.Ground)
+	copy(a.Tracks[:], d.Tracks)
+	copy(a.Icicles[:], d.Icicles)
+
+	for _, r := range a.residuals() {
+		err = r.set(d.Residual[r.name])
+		if err != nil {
+			return fmt.Errorf("%s: %v", r.name, err)
+		}
+	}
+
+	return nil
+}
+
+type areaCount struct {
+	name     string
+	count    *uint32
+	capacity int
+}
+
+// Every fixed array of a LevelArea with the field holding how many entries are in use
+func (a *LevelArea) counts() []areaCount {
+	return []areaCount{
+		{"Objects", &a.ObjectCount, len(a.Objects)},
+		{"Sounds", &a.SoundEffectCount, len(a.Sounds)},
+		{"Snakes", &a.SnakeBlockCount, len(a.Snakes)},
+		{"ClearPipes", &a.ClearPipeCount, len(a.ClearPipes)},
+		{"PiranhaCreepers", &a.PiranhaCreeperCount, len(a.PiranhaCreepers)},
+		{"ExclamationBlocks", &a.ExclamationMarkBlockCount, len(a.ExclamationBlocks)},
+		{"TrackBlocks", &a.TrackBlockCount, len(a.TrackBlocks)},
+		{"Ground", &a.GroundCount, len(a.Ground)},
+		{"Tracks", &a.TrackCount, len(a.Tracks)},
+		{"Icicles", &a.IceCount, len(a.Icicles)},
+	}
+}
+
+type areaResidual struct {
+	name string
+	get  func() ([]byte, error)
+	set  func([]byte) error
+}
+
+func newAreaResidual[T comparable](name string, entries []T, count *uint32) areaResidual {
+	return areaResidual{
+		name: name,
+		get: func() ([]byte, error) {
+			return encodeResidual(entries, int(*count))
+		},
+		set: func(raw []byte) error {
+			return decodeResidual(entries, int(*count), raw)
+		},
+	}
+}
+
+func (a *LevelArea) residuals() []areaResidual {
+	return []areaResidual{
+		newAreaResidual("Objects", a.Objects[:], &a.ObjectCount),
+		newAreaResidual("Sounds", a.Sounds[:], &a.SoundEffectCount),
+		newAreaResidual("Snakes", a.Snakes[:], &a.SnakeBlockCount),
+		newAreaResidual("ClearPipes", a.ClearPipes[:], &a.ClearPipeCount),
+		newAreaResidual("PiranhaCreepers", a.PiranhaCreepers[:], &a.PiranhaCreeperCount),
+		newAreaResidual("ExclamationBlocks", a.ExclamationBlocks[:], &a.ExclamationMarkBlockCount),
+		newAreaResidual("TrackBlocks", a.TrackBlocks[:], &a.TrackBlockCount),
+		newAreaResidual("Ground", a.Ground[:], &a.GroundCount),
+		newAreaResidual("Tracks", a.Tracks[:], &a.TrackCount),
+		newAreaResidual("Icicles", a.Icicles[:], &a.IceCount),
+	}
+}
+
+// Binary encoding of the entries past count up to the last non-zero one, nil if
+// they are all zero
+func encodeResidual[T comparable](entries []T, count int) ([]byte, error) {
+	var zero T
+	end := len(entries)
+	for end > count && entries[end-1] == zero {
+		end--
+	}
+	if end <= count {
+		return nil, nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := binary.Write(buf, binary.LittleEndian, entries[count:end])
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeResidual[T any](entries []T, count int, raw []byte) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	size := binary.Size(entries[0])
+	if len(raw)%size != 0 || count+len(raw)/size > len(entries) {
+		return fmt.Errorf("invalid residual size %d", len(raw))
+	}
+	return binary.Read(bytes.NewReader(raw), binary.LittleEndian, entries[count:count+len(raw)/size])
+}
+
+func splitNodes[T comparable](nodes []T, count int) ([]T, []byte, error) {
+	if count > len(nodes) {
+		return nil, nil, fmt.Errorf("node count %d > %d", count, len(nodes))
+	}
+	residual, err := encodeResidual(nodes, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]T{}, nodes[:count]...), residual, nil
+}
+
+func joinNodes[T any](dst []T, nodes []T, residual []byte) (uint8, error) {
+	if len(nodes) > len(dst) {
+		return 0, fmt.Errorf("too many nodes %d > %d", len(nodes), len(dst))
+	}
+	copy(dst, nodes)
+	return uint8(len(nodes)), decodeResidual(dst, len(nodes), residual)
+}
+
+// Unknown blocks are written without their trailing zeros, the rest of dst is
+// zeroed
+func copyFixed(dst []byte, src []byte, name string) error {
+	if len(src) > len(dst) {
+		return fmt.Errorf("%s must be at most %d bytes, got %d", name, len(dst), len(src))
+	}
+	n := copy(dst, src)
+	for i := n; i < len(dst); i++ {
+		dst[i] = 0
+	}
+	return nil
+}
+
+// Decode a null-terminated UCS-2 field, exact is false if encoding the result
+// again would not give back the same bytes
+func decodeUCS2Field(field []byte) (string, bool) {
+	end := len(field) &^ 1
+	for i := 0; i < end; i += 2 {
+		if field[i] == 0 && field[i+1] == 0 {
+			end = i
+			break
+		}
+	}
+
+	str, err := DecodeFromUCS2(field[:end])
+	if err != nil {
+		return "", false
+	}
+
+	check := make([]byte, len(field))
+	if encodeUCS2Field(check, str) != nil {
+		return str, false
+	}
+	return str, bytes.Equal(check, field)
+}
+
+func encodeUCS2Field(field []byte, str string) error {
+	encoded := EncodeToUCS2(str)
+	if len(encoded) > len(field) {
+		return fmt.Errorf("string too long %d > %d bytes", len(encoded), len(field))
+	}
+	copy(field, encoded)
+	for i := len(encoded); i < len(field); i++ {
+		field[i] = 0
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		level := newTestLevel(t, seed)
+		encoded, err := json.Marshal(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &BCD{}
+		err = json.Unmarshal(encoded, decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mustMarshal(t, level), mustMarshal(t, decoded)) {
+			t.Fatalf("seed %d: level changed after a JSON round trip", seed)
+		}
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	level := newEmptyBCD()
+	encoded, err := json.Marshal(map[string]*BCD{"level": level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without MarshalJSON all 2600 objects of each area are written
+	if strings.Count(string(encoded), `"LId"`) != 0 {
+		t.Fatalf("*BCD not marshalled by MarshalJSON: %.200s", encoded)
+	}
+}
@@ -0,0 +1,264 @@
+package smm2_parsing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Snake blocks, clear pipes, piranha creepers, ! blocks and track blocks all
+// store a path as a list of nodes. Clear pipe nodes have their own position,
+// the others move one tile per node from the position of the object that owns
+// the path. Paths are linked to that object by their Index field, which is
+// matched against Object.LId and then Object.SId of objects of the right Id.
+
+type PathKind uint8
+
+const (
+	PATH_SNAKE_BLOCK       PathKind = 0
+	PATH_CLEAR_PIPE        PathKind = 1
+	PATH_PIRANHA_CREEPER   PathKind = 2
+	PATH_EXCLAMATION_BLOCK PathKind = 3
+	PATH_TRACK_BLOCK       PathKind = 4
+)
+
+var pathKindNames = map[PathKind]string{
+	PATH_SNAKE_BLOCK:       "snake block",
+	PATH_CLEAR_PIPE:        "clear pipe",
+	PATH_PIRANHA_CREEPER:   "piranha creeper",
+	PATH_EXCLAMATION_BLOCK: "exclamation block",
+	PATH_TRACK_BLOCK:       "track block",
+}
+
+func (k PathKind) String() string {
+	return enumString(pathKindNames, k)
+}
+
+// Object that owns each kind of path
+var pathObjects = map[PathKind]ObjId{
+	PATH_SNAKE_BLOCK:       SNAKE_BLOCK,
+	PATH_CLEAR_PIPE:        CLEAR_PIPE,
+	PATH_PIRANHA_CREEPER:   PIRANHA_CREEPER,
+	PATH_EXCLAMATION_BLOCK: EXCLAMATION_BLOCK,
+	PATH_TRACK_BLOCK:       TRACK_BLOCK,
+}
+
+type PathTile struct {
+	X int
+	Y int
+}
+
+type PathNode struct {
+	Direction PathDirection
+	Tile      PathTile // Tile the node ends on
+}
+
+type Path struct {
+	Kind   PathKind
+	Entry  int      // Position in the area's array for Kind
+	Index  uint8    // Index field of the path
+	Object int      // Position of the owning object in Objects, -1 if not found
+	Start  PathTile // Tile of the owning object, for clear pipes the first node
+	Nodes  []PathNode
+}
+
+// Tile one step away in a direction, false for unknown directions. Y goes up.
+func (d PathDirection) Step(t PathTile) (PathTile, bool) {
+	switch d {
+	case DIRECTION_RIGHT:
+		t.X++
+	case DIRECTION_LEFT:
+		t.X--
+	case DIRECTION_DOWN:
+		t.Y--
+	case DIRECTION_UP:
+		t.Y++
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// Bottom left tile of an object, positions are its center
+func objectTile(o *Object) PathTile {
+	w := int(o.Width)
+	h := int(o.Height)
+	if w == 0 {
+		w = 1
+	}
+	if h == 0 {
+		h = 1
+	}
+	return PathTile{
+		X: (int(o.X) - w*OBJECT_TILE_UNITS/2) / OBJECT_TILE_UNITS,
+		Y: (int(o.Y) - h*OBJECT_TILE_UNITS/2) / OBJECT_TILE_UNITS,
+	}
+}
+
+// All paths in the area linked to their objects
+func (a *LevelArea) Paths() ([]Path, error) {
+	for _, c := range a.counts() {
+		if int(*c.count) > c.capacity {
+			return nil, fmt.Errorf("%s count %d > %d", c.name, *c.count, c.capacity)
+		}
+	}
+
+	var paths []Path
+	add := func(kind PathKind, entry int, index uint8, nodeCount uint8, capacity int, direction func(n int) PathDirection) {
+		if int(nodeCount) > capacity {
+			nodeCount = uint8(capacity)
+		}
+		p := Path{Kind: kind, Entry: entry, Index: index, Object: a.pathObject(kind, index)}
+		if p.Object >= 0 {
+			p.Start = objectTile(&a.Objects[p.Object])
+		}
+		tile := p.Start
+		for n := 0; n < int(nodeCount); n++ {
+			d := direction(n)
+			tile, _ = d.Step(tile)
+			p.Nodes = append(p.Nodes, PathNode{Direction: d, Tile: tile})
+		}
+		paths = append(paths, p)
+	}
+
+	for i := range a.Snakes[:a.SnakeBlockCount] {
+		s := &a.Snakes[i]
+		add(PATH_SNAKE_BLOCK, i, s.Index, s.NodeCount, len(s.Nodes), func(n int) PathDirection {
+			if s.Nodes[n].Direction > 0xFF {
+				return 0
+			}
+			return PathDirection(s.Nodes[n].Direction)
+		})
+	}
+	for i := range a.PiranhaCreepers[:a.PiranhaCreeperCount] {
+		c := &a.PiranhaCreepers[i]
+		add(PATH_PIRANHA_CREEPER, i, c.Index, c.NodeCount, len(c.Nodes), func(n int) PathDirection {
+			return PathDirection(c.Nodes[n].Direction)
+		})
+	}
+	for i := range a.ExclamationBlocks[:a.ExclamationMarkBlockCount] {
+		e := &a.ExclamationBlocks[i]
+		add(PATH_EXCLAMATION_BLOCK, i, e.Index, e.NodeCount, len(e.Nodes), func(n int) PathDirection {
+			return PathDirection(e.Nodes[n].Direction)
+		})
+	}
+	for i := range a.TrackBlocks[:a.TrackBlockCount] {
+		t := &a.TrackBlocks[i]
+		add(PATH_TRACK_BLOCK, i, t.Index, t.NodeCount, len(t.Nodes), func(n int) PathDirection {
+			return PathDirection(t.Nodes[n].Direction)
+		})
+	}
+
+	for i := range a.ClearPipes[:a.ClearPipeCount] {
+		c := &a.ClearPipes[i]
+		p := Path{Kind: PATH_CLEAR_PIPE, Entry: i, Index: c.Index, Object: a.pathObject(PATH_CLEAR_PIPE, c.Index)}
+		nodeCount := int(c.NodeCount)
+		if nodeCount > len(c.Nodes) {
+			nodeCount = len(c.Nodes)
+		}
+		for n, node := range c.Nodes[:nodeCount] {
+			tile := PathTile{int(node.X), int(node.Y)}
+			if n == 0 {
+				p.Start = tile
+			}
+			p.Nodes = append(p.Nodes, PathNode{Direction: PathDirection(node.Direction), Tile: tile})
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func (a *LevelArea) pathObject(kind PathKind, index uint8) int {
+	id := uint16(pathObjects[kind])
+	objects := a.Objects[:a.ObjectCount]
+	for i := range objects {
+		if objects[i].Id == id && objects[i].LId == uint16(index) {
+			return i
+		}
+	}
+	for i := range objects {
+		if objects[i].Id == id && objects[i].SId == uint16(index) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Broken paths: unknown directions, nodes past the capacity or outside the
+// area, paths without an object, objects shared by paths and path objects
+// without a path. All problems are returned, joined with errors.Join.
+func (a *LevelArea) ValidatePaths() error {
+	paths, err := a.Paths()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	width := int(a.BoundaryRight) / 16
+	height := int(a.BoundaryTop) / 16
+	owners := map[int]int{}
+	for i := range paths {
+		p := &paths[i]
+		name := fmt.Sprintf("%s %d", p.Kind, p.Entry)
+		if p.Object < 0 {
+			errs = append(errs, fmt.Errorf("%s: no %s object with link id %d", name, pathObjects[p.Kind], p.Index))
+		} else if other, ok := owners[p.Object]; ok {
+			errs = append(errs, fmt.Errorf("%s: object %d already used by %s %d", name, p.Object, paths[other].Kind, paths[other].Entry))
+		} else {
+			owners[p.Object] = i
+		}
+		if count, capacity := a.pathNodeCount(p.Kind, p.Entry), a.pathCapacity(p.Kind, p.Entry); count > capacity {
+			errs = append(errs, fmt.Errorf("%s: node count %d > %d", name, count, capacity))
+		}
+
+		for n, node := range p.Nodes {
+			if _, ok := pathDirectionNames[node.Direction]; !ok {
+				errs = append(errs, fmt.Errorf("%s: node %d has unknown direction %d", name, n, node.Direction))
+				// Later tiles are wrong too
+				break
+			}
+			if p.Object >= 0 && (node.Tile.X < 0 || node.Tile.Y < 0 || node.Tile.X >= width || node.Tile.Y >= height) {
+				errs = append(errs, fmt.Errorf("%s: node %d at %d,%d is outside the area", name, n, node.Tile.X, node.Tile.Y))
+				break
+			}
+		}
+	}
+
+	for i, o := range a.Objects[:a.ObjectCount] {
+		for kind, id := range pathObjects {
+			if o.Id == uint16(id) {
+				if _, ok := owners[i]; !ok {
+					errs = append(errs, fmt.Errorf("object %d: %s has no %s path", i, id, kind))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *LevelArea) pathNodeCount(kind PathKind, entry int) int {
+	switch kind {
+	case PATH_SNAKE_BLOCK:
+		return int(a.Snakes[entry].NodeCount)
+	case PATH_CLEAR_PIPE:
+		return int(a.ClearPipes[entry].NodeCount)
+	case PATH_PIRANHA_CREEPER:
+		return int(a.PiranhaCreepers[entry].NodeCount)
+	case PATH_EXCLAMATION_BLOCK:
+		return int(a.ExclamationBlocks[entry].NodeCount)
+	}
+	return int(a.TrackBlocks[entry].NodeCount)
+}
+
+func (a *LevelArea) pathCapacity(kind PathKind, entry int) int {
+	switch kind {
+	case PATH_SNAKE_BLOCK:
+		return len(a.Snakes[entry].Nodes)
+	case PATH_CLEAR_PIPE:
+		return len(a.ClearPipes[entry].Nodes)
+	case PATH_PIRANHA_CREEPER:
+		return len(a.PiranhaCreepers[entry].Nodes)
+	case PATH_EXCLAMATION_BLOCK:
+		return len(a.ExclamationBlocks[entry].Nodes)
+	}
+	return len(a.TrackBlocks[entry].Nodes)
+}
@@ -0,0 +1,98 @@
+package smm2_parsing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 1x1 object with its tile at x, y
+func tileObject(id ObjId, x int, y int, lid uint16) Object {
+	return Object{
+		Id:     uint16(id),
+		X:      uint32(x*OBJECT_TILE_UNITS + OBJECT_TILE_UNITS/2),
+		Y:      uint32(y*OBJECT_TILE_UNITS + OBJECT_TILE_UNITS/2),
+		Width:  1,
+		Height: 1,
+		LId:    lid,
+	}
+}
+
+func newPathTestArea() *LevelArea {
+	area := &newEmptyBCD().OverWorld
+	area.Objects[0] = tileObject(SNAKE_BLOCK, 10, 5, 1)
+	area.Objects[1] = tileObject(CLEAR_PIPE, 20, 2, 2)
+	area.ObjectCount = 2
+
+	area.Snakes[0].Index = 1
+	area.Snakes[0].NodeCount = 3
+	for i, d := range []PathDirection{DIRECTION_RIGHT, DIRECTION_RIGHT, DIRECTION_UP} {
+		area.Snakes[0].Nodes[i].Direction = uint16(d)
+	}
+	area.SnakeBlockCount = 1
+
+	area.ClearPipes[0].Index = 2
+	area.ClearPipes[0].NodeCount = 2
+	area.ClearPipes[0].Nodes[0] = ClearPipeNode{X: 20, Y: 2, Direction: uint8(DIRECTION_UP)}
+	area.ClearPipes[0].Nodes[1] = ClearPipeNode{X: 20, Y: 8, Direction: uint8(DIRECTION_RIGHT)}
+	area.ClearPipeCount = 1
+	return area
+}
+
+func TestPaths(t *testing.T) {
+	area := newPathTestArea()
+	paths, err := area.Paths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Path{
+		{Kind: PATH_SNAKE_BLOCK, Entry: 0, Index: 1, Object: 0, Start: PathTile{10, 5}, Nodes: []PathNode{
+			{DIRECTION_RIGHT, PathTile{11, 5}},
+			{DIRECTION_RIGHT, PathTile{12, 5}},
+			{DIRECTION_UP, PathTile{12, 6}},
+		}},
+		{Kind: PATH_CLEAR_PIPE, Entry: 0, Index: 2, Object: 1, Start: PathTile{20, 2}, Nodes: []PathNode{
+			{DIRECTION_UP, PathTile{20, 2}},
+			{DIRECTION_RIGHT, PathTile{20, 8}},
+		}},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths %+v, want %+v", paths, want)
+	}
+	if err := area.ValidatePaths(); err != nil {
+		t.Error(err)
+	}
+
+	area.SnakeBlockCount = uint32(len(area.Snakes) + 1)
+	if _, err := area.Paths(); err == nil {
+		t.Errorf("snake block count out of range accepted")
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	for _, test := range []struct {
+		change  func(a *LevelArea)
+		message string
+	}{
+		{func(a *LevelArea) { a.Snakes[0].Nodes[1].Direction = 9 }, "node 1 has unknown direction 9"},
+		{func(a *LevelArea) {
+			a.Snakes[0].Nodes[2].Direction = uint16(DIRECTION_DOWN)
+			a.Objects[0] = tileObject(SNAKE_BLOCK, 10, 0, 1)
+		}, "node 2 at 12,-1 is outside the area"},
+		{func(a *LevelArea) { a.Snakes[0].Index = 5 }, "no SNAKE_BLOCK object with link id 5"},
+		{func(a *LevelArea) { a.Snakes[0].Index = 5 }, "object 0: SNAKE_BLOCK has no snake block path"},
+		{func(a *LevelArea) { a.Snakes[1] = a.Snakes[0]; a.SnakeBlockCount = 2 }, "object 0 already used by snake block 0"},
+		{func(a *LevelArea) { a.Snakes[0].NodeCount = 200 }, "node count 200 > 120"},
+		{func(a *LevelArea) {
+			a.Objects[2] = tileObject(EXCLAMATION_BLOCK, 30, 3, 4)
+			a.ObjectCount = 3
+		}, "object 2: EXCLAMATION_BLOCK has no exclamation block path"},
+	} {
+		area := newPathTestArea()
+		test.change(area)
+		err := area.ValidatePaths()
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("ValidatePaths() = %v, want an error containing %q", err, test.message)
+		}
+	}
+}
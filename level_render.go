@@ -0,0 +1,97 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+)
+
+// Tile positions are bytes so areas are at most 256 tiles wide or tall, larger
+// boundaries are corrupt
+const renderMaxTiles = 256
+
+var (
+	renderSky    = color.RGBA{0x9C, 0xD8, 0xF8, 0xFF}
+	renderGround = color.RGBA{0x8C, 0x5A, 0x32, 0xFF}
+	renderTrack  = color.RGBA{0x40, 0x40, 0x40, 0xFF}
+	renderIcicle = color.RGBA{0xE0, 0xF8, 0xFF, 0xFF}
+)
+
+// Simple overview of an area, not what the game draws. Every tile is a
+// scale x scale square: ground is brown, tracks dark gray, icicles white and
+// objects a color picked from their Id so the same object always has the same
+// color.
+func RenderArea(area *LevelArea, scale int) (*image.RGBA, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("invalid scale %d", scale)
+	}
+	// Boundaries are in pixels, 16 per tile
+	width := int(area.BoundaryRight) / 16
+	height := int(area.BoundaryTop) / 16
+	if width <= 0 || height <= 0 || width > renderMaxTiles || height > renderMaxTiles {
+		return nil, fmt.Errorf("invalid area size %dx%d tiles", width, height)
+	}
+	if int(area.ObjectCount) > len(area.Objects) || int(area.GroundCount) > len(area.Ground) ||
+		int(area.TrackCount) > len(area.Tracks) || int(area.IceCount) > len(area.Icicles) {
+		return nil, fmt.Errorf("area counts out of range")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width*scale, height*scale))
+	// Y goes up in levels and down in images
+	fillTile := func(x int, y int, c color.RGBA) {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return
+		}
+		top := (height - 1 - y) * scale
+		for py := top; py < top+scale; py++ {
+			for px := x * scale; px < (x+1)*scale; px++ {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fillTile(x, y, renderSky)
+		}
+	}
+	for _, g := range area.Ground[:area.GroundCount] {
+		fillTile(int(g.X), int(g.Y), renderGround)
+	}
+	for _, t := range area.Tracks[:area.TrackCount] {
+		fillTile(int(t.X), int(t.Y), renderTrack)
+	}
+	for _, i := range area.Icicles[:area.IceCount] {
+		fillTile(int(i.X), int(i.Y), renderIcicle)
+	}
+
+	for _, o := range area.Objects[:area.ObjectCount] {
+		c := renderObjectColor(ObjId(o.Id))
+		w := int(o.Width)
+		h := int(o.Height)
+		if w == 0 {
+			w = 1
+		}
+		if h == 0 {
+			h = 1
+		}
+		// Positions are the center of the object, 160 units per tile
+		left := (int(o.X) - w*OBJECT_TILE_UNITS/2) / OBJECT_TILE_UNITS
+		bottom := (int(o.Y) - h*OBJECT_TILE_UNITS/2) / OBJECT_TILE_UNITS
+		for y := bottom; y < bottom+h; y++ {
+			for x := left; x < left+w; x++ {
+				fillTile(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
+
+func renderObjectColor(id ObjId) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(id.String()))
+	sum := h.Sum32()
+	// Keep colors saturated enough to stand out from the sky
+	return color.RGBA{uint8(sum) | 0x20, uint8(sum>>8) & 0xDF, uint8(sum>>16) | 0x10, 0xFF}
+}
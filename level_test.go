@@ -0,0 +1,54 @@
+package smm2_parsing
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Level with random entries in every array up to a random count, random
+// unknown fields and stale data past the counts, for round trip tests
+func newTestLevel(t testing.TB, seed int64) *BCD {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	buf := make([]byte, decryptedLevelSize)
+	r.Read(buf)
+
+	level := &BCD{}
+	err := level.LoadDecrypted(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(level.Header.Name[:], EncodeToUCS2("Test level\x00"))
+	copy(level.Header.Description[:], EncodeToUCS2("Made by newTestLevel\x00"))
+	level.Header.GameStyle = STYLE_M3
+	for _, area := range []*LevelArea{&level.OverWorld, &level.SubWorld} {
+		for _, c := range area.counts() {
+			*c.count = uint32(r.Intn(c.capacity + 1))
+		}
+		for i := range area.Snakes {
+			area.Snakes[i].NodeCount = uint8(r.Intn(len(area.Snakes[i].Nodes) + 1))
+		}
+		for i := range area.ClearPipes {
+			area.ClearPipes[i].NodeCount = uint8(r.Intn(len(area.ClearPipes[i].Nodes) + 1))
+		}
+		for i := range area.PiranhaCreepers {
+			area.PiranhaCreepers[i].NodeCount = uint8(r.Intn(len(area.PiranhaCreepers[i].Nodes) + 1))
+		}
+		for i := range area.ExclamationBlocks {
+			area.ExclamationBlocks[i].NodeCount = uint8(r.Intn(len(area.ExclamationBlocks[i].Nodes) + 1))
+		}
+		for i := range area.TrackBlocks {
+			area.TrackBlocks[i].NodeCount = uint8(r.Intn(len(area.TrackBlocks[i].Nodes) + 1))
+		}
+	}
+	return level
+}
+
+func mustMarshal(t testing.TB, level *BCD) []byte {
+	t.Helper()
+	buf, err := level.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
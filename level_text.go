@@ -0,0 +1,491 @@
+package smm2_parsing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Line based text format meant to be kept in version control. It is built from
+// the same document as the JSON export, for example:
+//
+//	[header]
+//	name = "My course"
+//	timelimit = 300
+//
+//	[overworld objects]
+//	GOOMBA x=12.5 y=1.5 w=1 h=1 wings
+//
+//	[overworld ground]
+//	ground x=3 y=0 id=5
+//
+// Object positions are written in tiles, everything else uses the raw values.
+// Keys and object names are case insensitive and fields left out are zero.
+
+const textFormatHeader = "# smm2 level text v1"
+
+var objectFlagNames = []struct {
+	name string
+	bit  uint32
+}{
+	{"wings", OBJFLAG_WINGS},
+	{"big", OBJFLAG_BIG},
+	{"parachute", OBJFLAG_PARACHUTE},
+}
+
+func (s *BCD) SaveText() ([]byte, error) {
+	doc := &jsonBCD{}
+	doc.Header.fromHeader(&s.Header)
+	err := doc.OverWorld.fromLevelArea(&s.OverWorld)
+	if err != nil {
+		return nil, fmt.Errorf("overworld: %v", err)
+	}
+	err = doc.SubWorld.fromLevelArea(&s.SubWorld)
+	if err != nil {
+		return nil, fmt.Errorf("subworld: %v", err)
+	}
+
+	w := &bytes.Buffer{}
+	fmt.Fprintln(w, textFormatHeader)
+	fmt.Fprintln(w, "[header]")
+	writeTextScalars(w, reflect.ValueOf(&doc.Header).Elem())
+
+	for _, area := range []struct {
+		name string
+		doc  *jsonLevelArea
+	}{{"overworld", &doc.OverWorld}, {"subworld", &doc.SubWorld}} {
+		fmt.Fprintf(w, "\n[%s]\n", area.name)
+		v := reflect.ValueOf(area.doc).Elem()
+		writeTextScalars(w, v)
+
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Struct || field.Len() == 0 {
+				continue
+			}
+			section := strings.ToLower(v.Type().Field(i).Name)
+			fmt.Fprintf(w, "\n[%s %s]\n", area.name, section)
+			for e := 0; e < field.Len(); e++ {
+				if section == "objects" {
+					writeTextObject(w, field.Index(e).Addr().Interface().(*jsonObject))
+				} else {
+					writeTextEntry(w, sectionTag(section), field.Index(e))
+				}
+			}
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+func (s *BCD) LoadText(buf []byte) error {
+	doc := &jsonBCD{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	// Residual blocks can make for very long lines
+	scanner.Buffer(nil, len(buf)+1)
+
+	var scalars reflect.Value
+	var entries reflect.Value
+	var area *jsonLevelArea
+	var section string
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var err error
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			names := strings.Fields(line[1 : len(line)-1])
+			scalars = reflect.Value{}
+			entries = reflect.Value{}
+			switch {
+			case len(names) == 1 && strings.EqualFold(names[0], "header"):
+				scalars = reflect.ValueOf(&doc.Header).Elem()
+				continue
+			case len(names) >= 1 && strings.EqualFold(names[0], "overworld"):
+				area = &doc.OverWorld
+			case len(names) >= 1 && strings.EqualFold(names[0], "subworld"):
+				area = &doc.SubWorld
+			default:
+				return fmt.Errorf("line %d: unknown section %s", lineNumber, line)
+			}
+			if area.Residual == nil {
+				area.Residual = map[string][]byte{}
+			}
+			if len(names) == 1 {
+				scalars = reflect.ValueOf(area).Elem()
+				continue
+			}
+			section = strings.ToLower(names[1])
+			entries = fieldByLowerName(reflect.ValueOf(area).Elem(), section)
+			if len(names) != 2 || !entries.IsValid() || entries.Kind() != reflect.Slice || entries.Type().Elem().Kind() != reflect.Struct {
+				return fmt.Errorf("line %d: unknown section %s", lineNumber, line)
+			}
+		} else if scalars.IsValid() {
+			err = parseTextScalar(scalars, line)
+		} else if entries.IsValid() {
+			if section == "objects" {
+				var object jsonObject
+				err = parseTextObject(&object, line)
+				area.Objects = append(area.Objects, object)
+			} else {
+				err = parseTextEntry(entries, sectionTag(section), line)
+			}
+		} else {
+			err = fmt.Errorf("outside of a section")
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return err
+	}
+
+	level := BCD{}
+	err = doc.Header.toHeader(&level.Header)
+	if err != nil {
+		return err
+	}
+	err = doc.OverWorld.toLevelArea(&level.OverWorld)
+	if err != nil {
+		return fmt.Errorf("overworld: %v", err)
+	}
+	err = doc.SubWorld.toLevelArea(&level.SubWorld)
+	if err != nil {
+		return fmt.Errorf("subworld: %v", err)
+	}
+	*s = level
+	return nil
+}
+
+// Tag at the start of every line in a section, "tracks" becomes "track"
+func sectionTag(section string) string {
+	if section == "ground" {
+		return section
+	}
+	return strings.TrimSuffix(section, "s")
+}
+
+func fieldByLowerName(v reflect.Value, name string) reflect.Value {
+	return v.FieldByNameFunc(func(field string) bool {
+		return strings.EqualFold(field, name)
+	})
+}
+
+func writeTextScalars(w *bytes.Buffer, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		name := strings.ToLower(v.Type().Field(i).Name)
+		switch {
+		case field.Kind() == reflect.Map:
+			iter := field.MapRange()
+			keys := []string{}
+			for iter.Next() {
+				keys = append(keys, iter.Key().String())
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(w, "%s.%s = %s\n", name, strings.ToLower(key), formatTextValue(field.MapIndex(reflect.ValueOf(key))))
+			}
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			// Written as its own section
+		case field.Kind() == reflect.Slice && field.IsNil():
+			// Raw fallbacks only present when needed
+		default:
+			fmt.Fprintf(w, "%s = %s\n", name, formatTextValue(field))
+		}
+	}
+}
+
+func parseTextScalar(v reflect.Value, line string) error {
+	key, value, found := strings.Cut(line, "=")
+	if !found {
+		return fmt.Errorf("expected key = value")
+	}
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	if name, arrayName, isMap := strings.Cut(key, "."); isMap {
+		field := fieldByLowerName(v, name)
+		if !field.IsValid() || field.Kind() != reflect.Map {
+			return fmt.Errorf("unknown key %s", key)
+		}
+		array := fieldByLowerName(v, arrayName)
+		if !array.IsValid() {
+			return fmt.Errorf("unknown key %s", key)
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return err
+		}
+		field.SetMapIndex(reflect.ValueOf(typeFieldName(v, arrayName)), reflect.ValueOf(raw))
+		return nil
+	}
+
+	field := fieldByLowerName(v, key)
+	if !field.IsValid() || field.Kind() == reflect.Map {
+		return fmt.Errorf("unknown key %s", key)
+	}
+	err := parseTextValue(field, value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+func typeFieldName(v reflect.Value, lower string) string {
+	for i := 0; i < v.NumField(); i++ {
+		if strings.EqualFold(v.Type().Field(i).Name, lower) {
+			return v.Type().Field(i).Name
+		}
+	}
+	return lower
+}
+
+func writeTextEntry(w *bytes.Buffer, tag string, v reflect.Value) {
+	w.WriteString(tag)
+	var nodes reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if v.Type().Field(i).Name == "Nodes" {
+			nodes = field
+			continue
+		}
+		if field.IsZero() {
+			continue
+		}
+		fmt.Fprintf(w, " %s=%s", strings.ToLower(v.Type().Field(i).Name), formatTextValue(field))
+	}
+	w.WriteString("\n")
+
+	if nodes.IsValid() {
+		for i := 0; i < nodes.Len(); i++ {
+			w.WriteString("  ")
+			writeTextEntry(w, "node", nodes.Index(i))
+		}
+	}
+}
+
+func parseTextEntry(entries reflect.Value, tag string, line string) error {
+	fields := strings.Fields(line)
+	var entry reflect.Value
+	switch {
+	case strings.EqualFold(fields[0], tag):
+		entries.Set(reflect.Append(entries, reflect.New(entries.Type().Elem()).Elem()))
+		entry = entries.Index(entries.Len() - 1)
+	case strings.EqualFold(fields[0], "node"):
+		if entries.Len() == 0 {
+			return fmt.Errorf("node without a %s", tag)
+		}
+		nodes := entries.Index(entries.Len() - 1).FieldByName("Nodes")
+		if !nodes.IsValid() {
+			return fmt.Errorf("%s has no nodes", tag)
+		}
+		nodes.Set(reflect.Append(nodes, reflect.New(nodes.Type().Elem()).Elem()))
+		entry = nodes.Index(nodes.Len() - 1)
+	default:
+		return fmt.Errorf("expected %s or node, got %s", tag, fields[0])
+	}
+
+	for _, token := range fields[1:] {
+		key, value, found := strings.Cut(token, "=")
+		field := fieldByLowerName(entry, key)
+		if !found || !field.IsValid() || strings.EqualFold(key, "nodes") {
+			return fmt.Errorf("unknown field %s", token)
+		}
+		err := parseTextValue(field, value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func writeTextObject(w *bytes.Buffer, o *jsonObject) {
+	fmt.Fprintf(w, "%s x=%s y=%s w=%d h=%d", o.Id, formatTiles(o.X), formatTiles(o.Y), o.Width, o.Height)
+	flag := o.Flag
+	for _, f := range objectFlagNames {
+		if flag&f.bit != 0 {
+			fmt.Fprintf(w, " %s", f.name)
+			flag &^= f.bit
+		}
+	}
+	if flag != 0 {
+		fmt.Fprintf(w, " flag=0x%x", flag)
+	}
+	if o.CFlag != 0 {
+		fmt.Fprintf(w, " cflag=0x%x", o.CFlag)
+	}
+	if o.CId != 0 {
+		fmt.Fprintf(w, " cid=%s", o.CId)
+	}
+	for _, field := range []struct {
+		name  string
+		value uint32
+	}{{"ex", o.Ex}, {"lid", uint32(o.LId)}, {"sid", uint32(o.SId)}, {"unk1", uint32(o.Unk1)}} {
+		if field.value != 0 {
+			fmt.Fprintf(w, " %s=%d", field.name, field.value)
+		}
+	}
+	w.WriteString("\n")
+}
+
+func parseTextObject(o *jsonObject, line string) error {
+	fields := strings.Fields(line)
+	// Names are upper case, accept any case like keys
+	err := o.Id.UnmarshalText([]byte(strings.ToUpper(fields[0])))
+	if err != nil {
+		return err
+	}
+
+fields:
+	for _, token := range fields[1:] {
+		key, value, found := strings.Cut(token, "=")
+		key = strings.ToLower(key)
+		if !found {
+			for _, f := range objectFlagNames {
+				if f.name == key {
+					o.Flag |= f.bit
+					continue fields
+				}
+			}
+			return fmt.Errorf("unknown flag %s", token)
+		}
+
+		var n uint64
+		switch key {
+		case "x":
+			o.X, err = parseTiles(value)
+		case "y":
+			o.Y, err = parseTiles(value)
+		case "cid":
+			err = o.CId.UnmarshalText([]byte(strings.ToUpper(value)))
+		case "w", "h", "flag", "cflag", "ex", "lid", "sid", "unk1":
+			n, err = strconv.ParseUint(value, 0, 32)
+		default:
+			return fmt.Errorf("unknown field %s", token)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if (key == "w" || key == "h") && n > 0xFF || (key == "lid" || key == "sid" || key == "unk1") && n > 0xFFFF {
+			return fmt.Errorf("%s out of range: %d", key, n)
+		}
+
+		switch key {
+		case "w":
+			o.Width = uint8(n)
+		case "h":
+			o.Height = uint8(n)
+		case "flag":
+			o.Flag |= uint32(n)
+		case "cflag":
+			o.CFlag = uint32(n)
+		case "ex":
+			o.Ex = uint32(n)
+		case "lid":
+			o.LId = uint16(n)
+		case "sid":
+			o.SId = uint16(n)
+		case "unk1":
+			o.Unk1 = uint16(n)
+		}
+	}
+	return nil
+}
+
+// Object units as tiles, exact since 160 only has 2 and 5 as prime factors
+func formatTiles(v uint32) string {
+	whole := v / OBJECT_TILE_UNITS
+	frac := v % OBJECT_TILE_UNITS
+	if frac == 0 {
+		return strconv.FormatUint(uint64(whole), 10)
+	}
+	return strings.TrimRight(fmt.Sprintf("%d.%05d", whole, frac*100000/OBJECT_TILE_UNITS), "0")
+}
+
+func parseTiles(s string) (uint32, error) {
+	wholeText, fracText, _ := strings.Cut(s, ".")
+	whole, err := strconv.ParseUint(wholeText, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if len(fracText) > 5 {
+		return 0, fmt.Errorf("too many decimals in %s", s)
+	}
+	frac := uint64(0)
+	if fracText != "" {
+		frac, err = strconv.ParseUint(fracText+strings.Repeat("0", 5-len(fracText)), 10, 32)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if frac*OBJECT_TILE_UNITS%100000 != 0 {
+		return 0, fmt.Errorf("%s is not a multiple of 1/%d tile", s, OBJECT_TILE_UNITS)
+	}
+	units := whole*OBJECT_TILE_UNITS + frac*OBJECT_TILE_UNITS/100000
+	if units > 0xFFFFFFFF {
+		return 0, fmt.Errorf("%s out of range", s)
+	}
+	return uint32(units), nil
+}
+
+func formatTextValue(v reflect.Value) string {
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := marshaler.MarshalText()
+		return string(text)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	default:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+}
+
+// Strings, byte slices, unsigned integers and TextUnmarshalers. Entry lists,
+// arrays, maps and structs have their own syntax and are an error here.
+func parseTextValue(v reflect.Value, s string) error {
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		str, err := strconv.Unquote(s)
+		if err != nil {
+			return err
+		}
+		v.SetString(str)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("is a list, not a value")
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(raw)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("can't be set from a value")
+	}
+	return nil
+}
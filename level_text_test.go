@@ -0,0 +1,95 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		level := newTestLevel(t, seed)
+		text, err := level.SaveText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &BCD{}
+		err = decoded.LoadText(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mustMarshal(t, level), mustMarshal(t, decoded)) {
+			t.Fatalf("seed %d: level changed after a text round trip", seed)
+		}
+	}
+}
+
+func TestTextObjectNameCase(t *testing.T) {
+	level := newEmptyBCD()
+	level.OverWorld.Objects[0] = Object{X: 400, Y: 240, Width: 1, Height: 1, Id: uint16(GOOMBA), CId: uint16(SUPER_MUSHROOM)}
+	level.OverWorld.ObjectCount = 1
+	text, err := level.SaveText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text = bytes.Replace(text, []byte("GOOMBA x="), []byte("Goomba x="), 1)
+	text = bytes.Replace(text, []byte("cid=SUPER_MUSHROOM"), []byte("cid=super_mushroom"), 1)
+	if !bytes.Contains(text, []byte("Goomba x=")) || !bytes.Contains(text, []byte("cid=super_mushroom")) {
+		t.Fatalf("unexpected object line in:\n%s", text)
+	}
+
+	decoded := &BCD{}
+	err = decoded.LoadText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.OverWorld.Objects[0] != level.OverWorld.Objects[0] {
+		t.Fatalf("got %+v, want %+v", decoded.OverWorld.Objects[0], level.OverWorld.Objects[0])
+	}
+}
+
+func TestTextMalformed(t *testing.T) {
+	for _, text := range []string{
+		"[overworld]\nobjects = AAAA\n",
+		"[overworld]\nsnakes = AAAA\n",
+		"[overworld]\nresidual = AAAA\n",
+		"[overworld]\nground = 1\n",
+		"[header]\nname = 1\n",
+		"[header]\ntimelimit = \"300\"\n",
+		"[header]\ntimelimit = 70000\n",
+		"[header]\nunk1 = !!!!\n",
+		"[header]\nnope = 1\n",
+		"[header]\nname\n",
+		"[overworld snakes]\nsnake NODES=AAAA\n",
+		"[overworld snakes]\nSNAKE nodes=AAAA\n",
+		"[overworld snakes]\nnode 1 2\n",
+		"[overworld snakes]\nsnake nope=1\n",
+		"[overworld objects]\nNOT_AN_OBJECT x=1\n",
+		"[overworld objects]\nGOOMBA x=nope\n",
+		"[overworld nope]\n",
+		"[overworld objects extra]\n",
+		"[overworld objectcount]\n",
+		"[nope]\n",
+		"objects = AAAA\n",
+	} {
+		err := (&BCD{}).LoadText([]byte(text))
+		if err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+func TestTextSectionCase(t *testing.T) {
+	text := "[Header]\nTimeLimit=300\n[OVERWORLD]\n[OverWorld Snakes]\nSnake Index=1\n  Node Index=2 Direction=3\n"
+	decoded := &BCD{}
+	err := decoded.LoadText([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snake := decoded.OverWorld.Snakes[0]
+	if decoded.OverWorld.SnakeBlockCount != 1 || snake.Index != 1 || snake.NodeCount != 1 || snake.Nodes[0].Index != 2 || snake.Nodes[0].Direction != 3 {
+		t.Fatalf("got %+v", snake)
+	}
+	if decoded.Header.TimeLimit != 300 {
+		t.Fatalf("got time limit %d", decoded.Header.TimeLimit)
+	}
+}
@@ -0,0 +1,163 @@
+package smm2_parsing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Where a track piece connects to others, in half tiles from the center of
+// the tile at its X, Y so pieces in neighbouring tiles meet on shared edges
+// and corners. Y goes up.
+type TrackPoint struct {
+	X int
+	Y int
+}
+
+type TrackShape struct {
+	Name string
+	Ends []TrackPoint // A single end for pieces that stop the track
+}
+
+// Shape of each Track.Type. The numbering comes from community notes and
+// hasn't been checked against levels from the game, types missing here are
+// reported in TrackGraph.Unknown.
+var trackShapes = map[uint8]TrackShape{
+	0: {"horizontal", []TrackPoint{{-1, 0}, {1, 0}}},
+	1: {"vertical", []TrackPoint{{0, -1}, {0, 1}}},
+	2: {"diagonal up", []TrackPoint{{-1, -1}, {1, 1}}},
+	3: {"diagonal down", []TrackPoint{{-1, 1}, {1, -1}}},
+	4: {"curve right down", []TrackPoint{{1, 0}, {0, -1}}},
+	5: {"curve left down", []TrackPoint{{-1, 0}, {0, -1}}},
+	6: {"curve right up", []TrackPoint{{1, 0}, {0, 1}}},
+	7: {"curve left up", []TrackPoint{{-1, 0}, {0, 1}}},
+	// End bumpers, named by the side the track continues on
+	8:  {"end left", []TrackPoint{{-1, 0}}},
+	9:  {"end right", []TrackPoint{{1, 0}}},
+	10: {"end down", []TrackPoint{{0, -1}}},
+	11: {"end up", []TrackPoint{{0, 1}}},
+}
+
+// Shape of a Track.Type, false for types without a known shape
+func TrackShapeOf(t uint8) (TrackShape, bool) {
+	shape, ok := trackShapes[t]
+	shape.Ends = append([]TrackPoint(nil), shape.Ends...)
+	return shape, ok
+}
+
+// Tracks connected by shared ends
+type TrackNetwork struct {
+	Tracks    []int        // Positions in LevelArea.Tracks
+	OpenEnds  []TrackPoint // Ends used by one track only, in half tiles from tile 0,0
+	Junctions []TrackPoint // Ends used by more than two tracks
+	Loop      bool         // The network contains a cycle
+	Riders    []int        // Positions in LevelArea.Objects of objects linked by LId
+}
+
+type TrackGraph struct {
+	Networks []TrackNetwork
+	Unknown  []int // Tracks with a Type without a known shape, see TrackShapeOf
+}
+
+// Connect the area's tracks into networks using TrackShapeOf
+func (a *LevelArea) TrackGraph() (*TrackGraph, error) {
+	if int(a.TrackCount) > len(a.Tracks) || int(a.ObjectCount) > len(a.Objects) {
+		return nil, fmt.Errorf("area counts out of range")
+	}
+	tracks := a.Tracks[:a.TrackCount]
+
+	// Union find over tracks, joined when they share an end
+	parent := make([]int, len(tracks))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	graph := &TrackGraph{}
+	ends := map[TrackPoint][]int{}
+	known := make([]bool, len(tracks))
+	for i, t := range tracks {
+		shape, ok := trackShapes[t.Type]
+		if !ok {
+			graph.Unknown = append(graph.Unknown, i)
+			continue
+		}
+		known[i] = true
+		for _, end := range shape.Ends {
+			p := TrackPoint{int(t.X)*2 + end.X, int(t.Y)*2 + end.Y}
+			if len(ends[p]) > 0 {
+				parent[find(i)] = find(ends[p][0])
+			}
+			ends[p] = append(ends[p], i)
+		}
+	}
+
+	networks := map[int]*TrackNetwork{}
+	var roots []int
+	for i := range tracks {
+		if !known[i] {
+			continue
+		}
+		root := find(i)
+		if networks[root] == nil {
+			networks[root] = &TrackNetwork{}
+			roots = append(roots, root)
+		}
+		networks[root].Tracks = append(networks[root].Tracks, i)
+	}
+
+	// A connected network without cycles has one less two ended track than
+	// it has distinct ends
+	edges := map[int]int{}
+	points := map[int]int{}
+	for p, users := range ends {
+		n := networks[find(users[0])]
+		points[find(users[0])]++
+		switch {
+		case len(users) == 1:
+			n.OpenEnds = append(n.OpenEnds, p)
+		case len(users) > 2:
+			n.Junctions = append(n.Junctions, p)
+		}
+	}
+	for i := range tracks {
+		if known[i] && len(trackShapes[tracks[i].Type].Ends) == 2 {
+			edges[find(i)]++
+		}
+	}
+
+	linked := map[uint16]int{}
+	for i, t := range tracks {
+		if known[i] && t.LId != 0 {
+			linked[t.LId] = find(i)
+		}
+	}
+	for i, o := range a.Objects[:a.ObjectCount] {
+		if root, ok := linked[o.LId]; ok && o.LId != 0 && ObjId(o.Id) != TRACK {
+			networks[root].Riders = append(networks[root].Riders, i)
+		}
+	}
+
+	for _, root := range roots {
+		n := networks[root]
+		n.Loop = edges[root] >= points[root]
+		sortTrackPoints(n.OpenEnds)
+		sortTrackPoints(n.Junctions)
+		graph.Networks = append(graph.Networks, *n)
+	}
+	return graph, nil
+}
+
+func sortTrackPoints(points []TrackPoint) {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].X != points[j].X {
+			return points[i].X < points[j].X
+		}
+		return points[i].Y < points[j].Y
+	})
+}
@@ -0,0 +1,90 @@
+package smm2_parsing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func addTracks(area *LevelArea, tracks ...Track) {
+	copy(area.Tracks[area.TrackCount:], tracks)
+	area.TrackCount += uint32(len(tracks))
+}
+
+func TestTrackGraph(t *testing.T) {
+	area := &newEmptyBCD().OverWorld
+	addTracks(area,
+		// Loop of four curves over tiles 0,0 to 1,1
+		Track{X: 0, Y: 0, Type: 6},
+		Track{X: 1, Y: 0, Type: 7},
+		Track{X: 0, Y: 1, Type: 4},
+		Track{X: 1, Y: 1, Type: 5},
+		// Straight line with an object riding it
+		Track{X: 5, Y: 5, Type: 0, LId: 7},
+		Track{X: 6, Y: 5, Type: 0, LId: 7},
+		// Three tracks meeting at one end
+		Track{X: 10, Y: 5, Type: 0},
+		Track{X: 11, Y: 5, Type: 0},
+		Track{X: 11, Y: 5, Type: 7},
+		Track{X: 20, Y: 5, Type: 200},
+	)
+	area.Objects[0] = Object{Id: uint16(LIFT), LId: 7}
+	area.Objects[1] = Object{Id: uint16(GOOMBA)}
+	area.ObjectCount = 2
+
+	graph, err := area.TrackGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TrackNetwork{
+		{Tracks: []int{0, 1, 2, 3}, Loop: true},
+		{Tracks: []int{4, 5}, OpenEnds: []TrackPoint{{9, 10}, {13, 10}}, Riders: []int{0}},
+		{Tracks: []int{6, 7, 8}, OpenEnds: []TrackPoint{{19, 10}, {22, 11}, {23, 10}}, Junctions: []TrackPoint{{21, 10}}},
+	}
+	if !reflect.DeepEqual(graph.Networks, want) {
+		t.Errorf("networks %+v, want %+v", graph.Networks, want)
+	}
+	if !reflect.DeepEqual(graph.Unknown, []int{9}) {
+		t.Errorf("unknown %v, want [9]", graph.Unknown)
+	}
+}
+
+// End bumpers close the track, only the other end is open
+func TestTrackGraphBumper(t *testing.T) {
+	area := &newEmptyBCD().OverWorld
+	addTracks(area,
+		Track{X: 5, Y: 5, Type: 0},
+		Track{X: 6, Y: 5, Type: 0},
+		Track{X: 7, Y: 5, Type: 8},
+		// Bumpers on both ends of a vertical line
+		Track{X: 20, Y: 4, Type: 11},
+		Track{X: 20, Y: 5, Type: 1},
+		Track{X: 20, Y: 6, Type: 10},
+	)
+
+	graph, err := area.TrackGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TrackNetwork{
+		{Tracks: []int{0, 1, 2}, OpenEnds: []TrackPoint{{9, 10}}},
+		{Tracks: []int{3, 4, 5}},
+	}
+	if !reflect.DeepEqual(graph.Networks, want) {
+		t.Errorf("networks %+v, want %+v", graph.Networks, want)
+	}
+}
+
+func TestTrackShapeOf(t *testing.T) {
+	shape, ok := TrackShapeOf(0)
+	if !ok || shape.Name != "horizontal" || len(shape.Ends) != 2 {
+		t.Fatalf("type 0 is %+v, %v", shape, ok)
+	}
+	// The table can't be changed through the result
+	shape.Ends[0] = TrackPoint{5, 5}
+	if again, _ := TrackShapeOf(0); again.Ends[0] != (TrackPoint{-1, 0}) {
+		t.Errorf("TrackShapeOf returned the table's slice")
+	}
+	if _, ok := TrackShapeOf(200); ok {
+		t.Errorf("type 200 has a shape")
+	}
+}
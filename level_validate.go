@@ -0,0 +1,81 @@
+package smm2_parsing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Structural problems that would make the game reject a level or make other
+// functions in this package misbehave. All problems are returned, joined with
+// errors.Join.
+func (s *BCD) Validate() error {
+	var errs []error
+
+	switch s.Header.GameStyle {
+	case STYLE_M1, STYLE_M3, STYLE_MW, STYLE_WU, STYLE_3W:
+	default:
+		errs = append(errs, fmt.Errorf("unknown game style %s", s.Header.GameStyle))
+	}
+	if err := s.Header.validateClearCondition(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := DecodeFromUCS2(s.Header.Name[:]); err != nil {
+		errs = append(errs, fmt.Errorf("name: %v", err))
+	}
+	if _, err := DecodeFromUCS2(s.Header.Description[:]); err != nil {
+		errs = append(errs, fmt.Errorf("description: %v", err))
+	}
+
+	for _, area := range []struct {
+		name string
+		area *LevelArea
+	}{{"overworld", &s.OverWorld}, {"subworld", &s.SubWorld}} {
+		errs = append(errs, area.area.validate(area.name)...)
+	}
+	return errors.Join(errs...)
+}
+
+func (a *LevelArea) validate(name string) []error {
+	var errs []error
+	if _, ok := courseThemeNames[CourseTheme(a.Theme)]; !ok {
+		errs = append(errs, fmt.Errorf("%s: unknown theme %d", name, a.Theme))
+	}
+
+	countsValid := true
+	for _, c := range a.counts() {
+		if int(*c.count) > c.capacity {
+			errs = append(errs, fmt.Errorf("%s: %s count %d > %d", name, c.name, *c.count, c.capacity))
+			countsValid = false
+		}
+	}
+	if !countsValid {
+		return errs
+	}
+
+	for i, o := range a.Snakes[:a.SnakeBlockCount] {
+		if int(o.NodeCount) > len(o.Nodes) {
+			errs = append(errs, fmt.Errorf("%s: snake %d node count %d > %d", name, i, o.NodeCount, len(o.Nodes)))
+		}
+	}
+	for i, o := range a.ClearPipes[:a.ClearPipeCount] {
+		if int(o.NodeCount) > len(o.Nodes) {
+			errs = append(errs, fmt.Errorf("%s: clear pipe %d node count %d > %d", name, i, o.NodeCount, len(o.Nodes)))
+		}
+	}
+	for i, o := range a.PiranhaCreepers[:a.PiranhaCreeperCount] {
+		if int(o.NodeCount) > len(o.Nodes) {
+			errs = append(errs, fmt.Errorf("%s: piranha creeper %d node count %d > %d", name, i, o.NodeCount, len(o.Nodes)))
+		}
+	}
+	for i, o := range a.ExclamationBlocks[:a.ExclamationMarkBlockCount] {
+		if int(o.NodeCount) > len(o.Nodes) {
+			errs = append(errs, fmt.Errorf("%s: exclamation block %d node count %d > %d", name, i, o.NodeCount, len(o.Nodes)))
+		}
+	}
+	for i, o := range a.TrackBlocks[:a.TrackBlockCount] {
+		if int(o.NodeCount) > len(o.Nodes) {
+			errs = append(errs, fmt.Errorf("%s: track block %d node count %d > %d", name, i, o.NodeCount, len(o.Nodes)))
+		}
+	}
+	return errs
+}
@@ -201,7 +201,6 @@ func (s *Replay) HandleTimePassed(units int) {
 		s.entries = append(s.entries, entry)
 
 		// Not yet in sync, TODO
-		fmt.Printf("Frames passed %d\n", units)
 		s.totalFrames += units
 	}
 }
@@ -254,6 +253,10 @@ func (s *Replay) Load(buf []byte) error {
 
 	ended := false
 	for !ended {
+		// Truncated or corrupt replays would otherwise loop forever on zeros
+		if reader.Len() == 0 {
+			return fmt.Errorf("Replay did not end properly")
+		}
 		_, _ = reader.ReadByte()
 
 		includeEnding := true
@@ -261,6 +264,9 @@ func (s *Replay) Load(buf []byte) error {
 		for {
 			//pos, _ := reader.Seek(0, 1)
 			//fmt.Printf("Pos111 %x\n", pos)
+			if reader.Len() == 0 {
+				return fmt.Errorf("Replay did not end properly")
+			}
 
 			key, _ := reader.ReadByte()
 			time, _ := reader.ReadByte()
@@ -293,6 +299,9 @@ func (s *Replay) Load(buf []byte) error {
 				for {
 					//pos, _ := reader.Seek(0, 1)
 					//fmt.Printf("Pos %x\n", pos)
+					if reader.Len() == 0 {
+						return fmt.Errorf("Replay did not end properly")
+					}
 
 					var joysticks byte = 0
 
@@ -309,9 +318,11 @@ func (s *Replay) Load(buf []byte) error {
 							// Joysticks
 							s.HandleJoysticks(reader)
 
-							checkReadAgain, _ := reader.ReadByte()
-							// Go backwards one byte
-							reader.Seek(-1, 1)
+							checkReadAgain, err := reader.ReadByte()
+							if err == nil {
+								// Go backwards one byte
+								reader.UnreadByte()
+							}
 
 							if checkReadAgain == 0x80 {
 								break
@@ -345,9 +356,11 @@ func (s *Replay) Load(buf []byte) error {
 							break
 						} else {
 							// Try to continue joysticks
-							checkReadAgain, _ := reader.ReadByte()
-							// Go backwards one byte
-							reader.Seek(-1, 1)
+							checkReadAgain, err := reader.ReadByte()
+							if err == nil {
+								// Go backwards one byte
+								reader.UnreadByte()
+							}
 
 							if checkReadAgain == 0x80 {
 								break
@@ -372,8 +385,6 @@ func (s *Replay) Load(buf []byte) error {
 	binary.Read(reader, binary.LittleEndian, unk9)
 
 	currentPosition, _ := reader.Seek(0, 1)
-	fmt.Printf("End %d %d\n", currentPosition, reader.Size())
-	fmt.Printf("All frames %d\n", s.totalFrames)
 
 	if currentPosition == reader.Size() {
 		return nil
@@ -0,0 +1,41 @@
+package smm2_parsing
+
+import "encoding/json"
+
+type jsonReplayEntry struct {
+	Type      string   // "time", "joysticks" or "inputs"
+	Frames    uint8    `json:",omitempty"`
+	JoystickX int16    `json:",omitempty"`
+	JoystickY int16    `json:",omitempty"`
+	Inputs    []string `json:",omitempty"`
+}
+
+type jsonReplay struct {
+	TotalFrames int
+	Entries     []jsonReplayEntry
+}
+
+// Entries in file order, inputs use the names from InputToName
+func (s *Replay) MarshalJSON() ([]byte, error) {
+	doc := jsonReplay{TotalFrames: s.totalFrames, Entries: []jsonReplayEntry{}}
+	for _, entry := range s.entries {
+		var e jsonReplayEntry
+		switch entry.entry_type {
+		case Time:
+			e.Type = "time"
+			e.Frames = entry.frames
+		case Joysticks:
+			e.Type = "joysticks"
+			e.JoystickX = entry.joystick_x
+			e.JoystickY = entry.joystick_y
+		case Inputs:
+			e.Type = "inputs"
+			e.Inputs = []string{}
+			for _, input := range entry.inputs {
+				e.Inputs = append(e.Inputs, s.InputToName(input))
+			}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,226 @@
+// Package smm2http exposes smm2_parsing over HTTP.
+//
+// All endpoints take the file as the POST body:
+//
+//	POST /level      encrypted level, returns LevelInfo as JSON (?full=1 for the whole level)
+//	POST /render     encrypted level, returns a PNG (?subworld=1, ?scale=n)
+//	POST /thumbnail  JPEG, returns the encrypted thumbnail
+//	POST /replay     replay, returns the nx-tas script (?format=json for JSON)
+//
+// Errors are returned as JSON, see ErrorResponse.
+package smm2http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/mm2srv/smm2_parsing"
+)
+
+const (
+	maxLevelSize     = 0x5c000
+	maxThumbnailSize = 0x1c000
+	// Replays have no fixed size, the longest seen are far below this
+	maxReplaySize  = 0x100000
+	maxRenderScale = 16
+)
+
+type ErrorCode string
+
+const (
+	ErrMethodNotAllowed ErrorCode = "method_not_allowed"
+	ErrTooLarge         ErrorCode = "too_large"
+	ErrInvalidArgument  ErrorCode = "invalid_argument"
+	ErrInvalidLevel     ErrorCode = "invalid_level"
+	ErrInvalidThumbnail ErrorCode = "invalid_thumbnail"
+	ErrInvalidReplay    ErrorCode = "invalid_replay"
+	ErrInternal         ErrorCode = "internal"
+)
+
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// Body of every non 2xx response
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type ClearCondition struct {
+	Category  smm2_parsing.ClearConCategory
+	Object    smm2_parsing.ClearConId
+	Magnitude uint16
+}
+
+// Returned by POST /level
+type LevelInfo struct {
+	Name           string
+	Description    string
+	Style          smm2_parsing.GameStyle
+	GameVersion    smm2_parsing.GameVersion
+	TimeLimit      uint16
+	ClearCondition ClearCondition
+	CourseId       string `json:",omitempty"` // Only for uploaded levels
+	OverWorldTheme smm2_parsing.CourseTheme
+	SubWorldTheme  smm2_parsing.CourseTheme
+	Objects        uint32 // Over both areas
+}
+
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/level", post(maxLevelSize, handleLevel))
+	mux.HandleFunc("/render", post(maxLevelSize, handleRender))
+	mux.HandleFunc("/thumbnail", post(maxThumbnailSize, handleThumbnail))
+	mux.HandleFunc("/replay", post(maxReplaySize, handleReplay))
+	return mux
+}
+
+// Only allow POST and read the body up to limit bytes
+func post(limit int64, handler func(http.ResponseWriter, *http.Request, []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed, "only POST is allowed")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, ErrTooLarge, fmt.Sprintf("body larger than %d bytes", limit))
+				return
+			}
+			writeError(w, http.StatusBadRequest, ErrInvalidArgument, err.Error())
+			return
+		}
+		handler(w, r, body)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	writeJSON(w, status, ErrorResponse{Error{code, message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		buf, _ = json.Marshal(ErrorResponse{Error{ErrInternal, err.Error()}})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(buf, '\n'))
+}
+
+func handleLevel(w http.ResponseWriter, r *http.Request, body []byte) {
+	level, err := smm2_parsing.LoadBCD(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidLevel, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("full") == "1" {
+		writeJSON(w, http.StatusOK, level)
+		return
+	}
+
+	h := &level.Header
+	info := LevelInfo{
+		Name:        h.CourseName(),
+		Description: h.CourseDescription(),
+		Style:       h.GameStyle,
+		GameVersion: smm2_parsing.GameVersion(h.GameVersion),
+		TimeLimit:   h.TimeLimit,
+		ClearCondition: ClearCondition{
+			Category:  smm2_parsing.ClearConCategory(h.ClearConditionCategory),
+			Object:    smm2_parsing.ClearConId(h.ClearConditionObject),
+			Magnitude: h.ClearConditionMagnitude,
+		},
+		OverWorldTheme: smm2_parsing.CourseTheme(level.OverWorld.Theme),
+		SubWorldTheme:  smm2_parsing.CourseTheme(level.SubWorld.Theme),
+		Objects:        level.OverWorld.ObjectCount + level.SubWorld.ObjectCount,
+	}
+	if h.UploadId != 0 {
+		info.CourseId, _ = smm2_parsing.DataIdToCourseId(h.UploadId)
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func handleRender(w http.ResponseWriter, r *http.Request, body []byte) {
+	scale := 4
+	if s := r.URL.Query().Get("scale"); s != "" {
+		var err error
+		scale, err = strconv.Atoi(s)
+		if err != nil || scale < 1 || scale > maxRenderScale {
+			writeError(w, http.StatusBadRequest, ErrInvalidArgument, fmt.Sprintf("scale must be 1-%d", maxRenderScale))
+			return
+		}
+	}
+
+	level, err := smm2_parsing.LoadBCD(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidLevel, err.Error())
+		return
+	}
+	area := &level.OverWorld
+	if r.URL.Query().Get("subworld") == "1" {
+		area = &level.SubWorld
+	}
+	img, err := smm2_parsing.RenderArea(area, scale)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidLevel, err.Error())
+		return
+	}
+
+	out := &bytes.Buffer{}
+	err = png.Encode(out, img)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, ErrInternal, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(out.Bytes())
+}
+
+func handleThumbnail(w http.ResponseWriter, r *http.Request, body []byte) {
+	// Anything else would be re-encoded by EncryptJpegThumbnail if it has to
+	// be shrunk and stored as is otherwise
+	_, err := smm2_parsing.InspectJpeg(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidThumbnail, err.Error())
+		return
+	}
+	thumbnail, err := smm2_parsing.EncryptJpegThumbnail(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidThumbnail, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(thumbnail)
+}
+
+func handleReplay(w http.ResponseWriter, r *http.Request, body []byte) {
+	replay, err := smm2_parsing.DecodeReplay(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrInvalidReplay, err.Error())
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "tas":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, replay.GetTASText())
+	case "json":
+		writeJSON(w, http.StatusOK, replay)
+	default:
+		writeError(w, http.StatusBadRequest, ErrInvalidArgument, "format must be tas or json")
+	}
+}
@@ -0,0 +1,191 @@
+package smm2http
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mm2srv/smm2_parsing"
+)
+
+func doPost(t *testing.T, path string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	NewHandler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	return w
+}
+
+func TestThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 360)), nil); err != nil {
+		t.Fatal(err)
+	}
+	w := doPost(t, "/thumbnail", buf.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if err := smm2_parsing.VerifyThumbnail(w.Body.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestThumbnailNotJpeg(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 360))); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range [][]byte{buf.Bytes(), []byte("not an image"), nil} {
+		w := doPost(t, "/thumbnail", body)
+		var response ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusBadRequest || response.Error.Code != ErrInvalidThumbnail {
+			t.Errorf("status %d, code %s", w.Code, response.Error.Code)
+		}
+	}
+}
+
+func testLevel(t *testing.T) []byte {
+	t.Helper()
+	// Any tile for any neighbour mask, the handlers don't look at ground Ids
+	tileset := smm2_parsing.GroundTileset{}
+	for mask := 0; mask < 256; mask++ {
+		tileset[uint8(mask)] = 0
+	}
+	level, err := smm2_parsing.NewLevelBuilder().
+		Name("Handler test").
+		Tileset(tileset).
+		Place(smm2_parsing.GOOMBA, 10, 1).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := level.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+// Shortest replay Load accepts: header, end cap, the end of file key and the
+// trailing 4 bytes
+func testReplay() []byte {
+	buf := make([]byte, 0x61)
+	buf = append(buf, 0x40)
+	buf = append(buf, make([]byte, 7)...)
+	buf = append(buf, 0x00, 0x00, 0x10)
+	return append(buf, make([]byte, 4)...)
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) ErrorCode {
+	t.Helper()
+	var response ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("error body %q: %v", w.Body, err)
+	}
+	return response.Error.Code
+}
+
+func TestEndpoints(t *testing.T) {
+	level := testLevel(t)
+	replay := testReplay()
+	for _, test := range []struct {
+		path        string
+		body        []byte
+		status      int
+		contentType string
+		code        ErrorCode // For errors
+	}{
+		{"/level", level, http.StatusOK, "application/json", ""},
+		{"/level?full=1", level, http.StatusOK, "application/json", ""},
+		{"/level", level[:len(level)-1], http.StatusBadRequest, "application/json", ErrInvalidLevel},
+		{"/render", level, http.StatusOK, "image/png", ""},
+		{"/render?scale=1", level, http.StatusOK, "image/png", ""},
+		{"/render?scale=16", level, http.StatusOK, "image/png", ""},
+		{"/render?subworld=1", level, http.StatusOK, "image/png", ""},
+		{"/render?scale=0", level, http.StatusBadRequest, "application/json", ErrInvalidArgument},
+		{"/render?scale=17", level, http.StatusBadRequest, "application/json", ErrInvalidArgument},
+		{"/render?scale=big", level, http.StatusBadRequest, "application/json", ErrInvalidArgument},
+		{"/replay", replay, http.StatusOK, "text/plain; charset=utf-8", ""},
+		{"/replay?format=tas", replay, http.StatusOK, "text/plain; charset=utf-8", ""},
+		{"/replay?format=json", replay, http.StatusOK, "application/json", ""},
+		{"/replay?format=xml", replay, http.StatusBadRequest, "application/json", ErrInvalidArgument},
+		{"/replay", replay[:len(replay)-5], http.StatusBadRequest, "application/json", ErrInvalidReplay},
+		{"/level", make([]byte, maxLevelSize+1), http.StatusRequestEntityTooLarge, "application/json", ErrTooLarge},
+		{"/thumbnail", make([]byte, maxThumbnailSize+1), http.StatusRequestEntityTooLarge, "application/json", ErrTooLarge},
+		{"/replay", make([]byte, maxReplaySize+1), http.StatusRequestEntityTooLarge, "application/json", ErrTooLarge},
+	} {
+		w := doPost(t, test.path, test.body)
+		if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("%s: status %d, content type %s: %.200s", test.path, w.Code, w.Header().Get("Content-Type"), w.Body)
+			continue
+		}
+		if test.code != "" {
+			if code := errorCode(t, w); code != test.code {
+				t.Errorf("%s: error code %s, want %s", test.path, code, test.code)
+			}
+		}
+	}
+}
+
+func TestLevelInfo(t *testing.T) {
+	w := doPost(t, "/level", testLevel(t))
+	var info LevelInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Handler test" || info.Objects != 1 || info.CourseId != "" {
+		t.Errorf("got %+v", info)
+	}
+
+	// The whole level round trips through the BCD JSON
+	w = doPost(t, "/level?full=1", testLevel(t))
+	level := &smm2_parsing.BCD{}
+	if err := json.Unmarshal(w.Body.Bytes(), level); err != nil {
+		t.Fatal(err)
+	}
+	if level.Header.CourseName() != "Handler test" || level.OverWorld.ObjectCount != 1 {
+		t.Errorf("full level %q with %d objects", level.Header.CourseName(), level.OverWorld.ObjectCount)
+	}
+}
+
+func TestRenderScale(t *testing.T) {
+	level := testLevel(t)
+	sizes := map[string]image.Point{}
+	for _, path := range []string{"/render?scale=1", "/render?scale=2", "/render?scale=1&subworld=1"} {
+		w := doPost(t, path, level)
+		img, err := png.Decode(w.Body)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		sizes[path] = img.Bounds().Size()
+	}
+	if sizes["/render?scale=2"] != sizes["/render?scale=1"].Mul(2) {
+		t.Errorf("scale 2 is %v, scale 1 %v", sizes["/render?scale=2"], sizes["/render?scale=1"])
+	}
+	if sizes["/render?scale=1&subworld=1"] == (image.Point{}) {
+		t.Errorf("empty subworld render")
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	for _, path := range []string{"/level", "/render", "/thumbnail", "/replay"} {
+		for _, method := range []string{http.MethodGet, http.MethodPut} {
+			w := httptest.NewRecorder()
+			NewHandler().ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("%s %s: status %d, Allow %q", method, path, w.Code, w.Header().Get("Allow"))
+				continue
+			}
+			if code := errorCode(t, w); code != ErrMethodNotAllowed {
+				t.Errorf("%s %s: error code %s", method, path, code)
+			}
+		}
+	}
+}
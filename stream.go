@@ -0,0 +1,98 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Read exactly one encrypted level (0x5c000 bytes) from r, the level is
+// decrypted in place so only one buffer is allocated
+func DecodeBCD(r io.Reader) (*BCD, error) {
+	buf := make([]byte, 0x5c000)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, fmt.Errorf("reading level: %w", err)
+	}
+
+	decrypted := buf[0x10:0x5BFD0]
+	err = decryptLevelInto(decrypted, buf, &bytes.Buffer{})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BCD{}
+	decodeBCD(s, decrypted)
+	return s, nil
+}
+
+// Read exactly one decrypted level (0x5BFC0 bytes) from r
+func DecodeDecryptedBCD(r io.Reader) (*BCD, error) {
+	decrypted := levelBufferPool.Get().(*[decryptedLevelSize]byte)
+	defer levelBufferPool.Put(decrypted)
+	_, err := io.ReadFull(r, decrypted[:])
+	if err != nil {
+		return nil, fmt.Errorf("reading level: %w", err)
+	}
+
+	s := &BCD{}
+	decodeBCD(s, decrypted[:])
+	return s, nil
+}
+
+// Write the encrypted level to w, same as Save. The level is encoded and
+// encrypted in the buffer that is written
+func (s *BCD) WriteTo(w io.Writer) (int64, error) {
+	buf, err := s.encrypt(nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// Write the decrypted level to w, same as SaveDecrypted
+func (s *BCD) WriteDecryptedTo(w io.Writer) (int64, error) {
+	decrypted := levelBufferPool.Get().(*[decryptedLevelSize]byte)
+	defer levelBufferPool.Put(decrypted)
+	encodeBCD(decrypted[:], s)
+	n, err := w.Write(decrypted[:])
+	return int64(n), err
+}
+
+// Read a replay until EOF, replays have no fixed size
+func DecodeReplay(r io.Reader) (*Replay, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Replay{}
+	err = s.Load(buf)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Read exactly one encrypted thumbnail (0x1C000 bytes) from r, verify it and
+// return the JPEG inside, see DecryptJpegThumbnail
+func DecodeThumbnail(r io.Reader) ([]byte, error) {
+	buf := make([]byte, 0x1C000)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, fmt.Errorf("reading thumbnail: %w", err)
+	}
+
+	return DecryptJpegThumbnail(buf)
+}
+
+// Encrypt a JPEG with EncryptJpegThumbnail and write it to w
+func EncodeThumbnail(w io.Writer, buf []byte) error {
+	out, err := EncryptJpegThumbnail(buf)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
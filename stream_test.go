@@ -0,0 +1,100 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestStreamLevelRoundTrip(t *testing.T) {
+	level := newTestLevel(t, 0)
+
+	var buf bytes.Buffer
+	n, err := level.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0x5c000 || buf.Len() != 0x5c000 {
+		t.Fatalf("wrote %d bytes, buffer has %d", n, buf.Len())
+	}
+	if err := VerifyLevel(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	// Two levels back to back are read one at a time
+	buf.Write(buf.Bytes())
+	for i := 0; i < 2; i++ {
+		decoded, err := DecodeBCD(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, level) {
+			t.Fatalf("level %d changed after WriteTo and DecodeBCD", i)
+		}
+	}
+
+	n, err = level.WriteDecryptedTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != decryptedLevelSize || !bytes.Equal(buf.Bytes(), mustMarshal(t, level)) {
+		t.Fatalf("WriteDecryptedTo wrote %d bytes that differ from MarshalBinary", n)
+	}
+	decoded, err := DecodeDecryptedBCD(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, level) {
+		t.Fatal("level changed after WriteDecryptedTo and DecodeDecryptedBCD")
+	}
+}
+
+func TestStreamThumbnailRoundTrip(t *testing.T) {
+	jpegBuf := testJpeg(t)
+	var buf bytes.Buffer
+	if err := EncodeThumbnail(&buf, jpegBuf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0x1C000 {
+		t.Fatalf("wrote %d bytes", buf.Len())
+	}
+	decoded, err := DecodeThumbnail(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, jpegBuf) {
+		t.Fatal("thumbnail changed after EncodeThumbnail and DecodeThumbnail")
+	}
+}
+
+func TestStreamShortReader(t *testing.T) {
+	level := newTestLevel(t, 0)
+	encrypted, err := level.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := EncryptJpegThumbnail(testJpeg(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		decode func(io.Reader) error
+		buf    []byte
+	}{
+		{"DecodeBCD", func(r io.Reader) error { _, err := DecodeBCD(r); return err }, encrypted},
+		{"DecodeDecryptedBCD", func(r io.Reader) error { _, err := DecodeDecryptedBCD(r); return err }, mustMarshal(t, level)},
+		{"DecodeThumbnail", func(r io.Reader) error { _, err := DecodeThumbnail(r); return err }, thumbnail},
+	} {
+		err := test.decode(bytes.NewReader(test.buf[:len(test.buf)-1]))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: got %v, want io.ErrUnexpectedEOF", test.name, err)
+		}
+		err = test.decode(bytes.NewReader(nil))
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("%s on an empty reader: got %v, want io.EOF", test.name, err)
+		}
+	}
+}
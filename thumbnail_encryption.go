@@ -4,10 +4,8 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/binary"
+	"errors"
 	"fmt"
-	"image"
-	"image/jpeg"
 )
 
 var thumbnailTable = []uint32{
@@ -29,76 +27,54 @@ var thumbnailTable = []uint32{
 	0xfcb26110, 0x00ad3d74, 0xc0e73a4b, 0xf132e7c7,
 }
 
+// Returns the JPEG inside a thumbnail, only re-encoded if InspectJpeg finds
+// something the game might not accept
 func UnpackJpegThumbnail(buf []byte) ([]byte, error) {
 	if len(buf) != 0x1c000 {
 		return []byte{}, fmt.Errorf("invalid buf size %d != %d", len(buf), 0x1c000)
 	}
 
-	reader := bytes.NewReader(buf)
-	img, _, err := image.Decode(reader)
-	if err != nil {
-		return nil, err
+	if bytes.Equal(buf[0x1Bf9C:(0x1BF9C+4)], []byte{0x9C, 0xBF, 0x01, 0x00}) {
+		buf = bytes.TrimRight(buf[:0x1BF9C], "\x00")
 	}
 
-	out := &bytes.Buffer{}
-	err = jpeg.Encode(out, img, &jpeg.Options{Quality: 65})
+	out, _, err := RepackThumbnailIfNeeded(buf)
 	if err != nil {
 		return nil, err
 	}
-
-	return out.Bytes(), nil
+	return append([]byte{}, out...), nil
 }
 
-// no clue why but (some) dump world thumbnails fail otherwise
+// Some dumped world thumbnails are progressive or otherwise differ from what
+// image/jpeg writes, see RepackThumbnailIfNeeded. Their size is kept.
 func RepackWorldThumbnailSilly(buf []byte) ([]byte, error) {
-	reader := bytes.NewReader(buf)
-	img, _, err := image.Decode(reader)
-	if err != nil {
-		return nil, err
-	}
-
-	out := &bytes.Buffer{}
-	err = jpeg.Encode(out, img, &jpeg.Options{Quality: 65})
-	if err != nil {
-		return nil, err
-	}
-
-	return out.Bytes(), nil
+	out, _, err := repackJpeg(buf, false)
+	return out, err
 }
 
+// Shrink a thumbnail to at most 0x1BF9C bytes with DefaultFitOptions, see FitThumbnail
 func RepackThumbnailUntilFit(buf []byte) ([]byte, error) {
-	fmt.Printf("WARNING repacking thumbnail because jpeg is too large %d > %d\n", len(buf), 0x1BF9C)
-	reader := bytes.NewReader(buf)
-	img, _, err := image.Decode(reader)
+	out, _, err := FitThumbnail(buf, &DefaultFitOptions)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("RepackThumbnailUntilFit: %v", err)
 	}
-
-	out := &bytes.Buffer{}
-	for _, quality := range []int{95, 85, 75, 65, 55, 45, 20} {
-		out.Reset()
-		err = jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
-		if err != nil {
-			return nil, err
-		}
-
-		if out.Len() < 0x1BF9C {
-			return out.Bytes(), nil
-		}
-	}
-
-	return out.Bytes(), fmt.Errorf("RepackThumbnailUntilFit unable to shrink enough %v > %v", out.Len(), 0x1C000)
+	return out, nil
 }
 
 // Add neccesary data at the end of the thumbnail
 func EncryptJpegThumbnail(buf []byte) ([]byte, error) {
+	return EncryptJpegThumbnailWithOptions(buf, nil)
+}
+
+// EncryptJpegThumbnail with the seed from opts, nil uses crypto/rand
+func EncryptJpegThumbnailWithOptions(buf []byte, opts *EncryptOptions) ([]byte, error) {
 	// return if already an encrypted thumbnail
 	if len(buf) == 0x1C000 && bytes.Equal(buf[0x1Bf9C:(0x1BF9C+4)], []byte{0x9C, 0xBF, 0x01, 0x00}) {
 		return buf, nil
 	}
 
 	// 0x1BF9C is unecrypted thumbnail, 0x1C000 is already encrypted but will be reencrypted just in case
-	if len(buf) > 0x1BF9C {
+	if len(buf) > thumbnailJpegMaxSize {
 		var err error
 		buf, err = RepackThumbnailUntilFit(buf)
 		if err != nil {
@@ -109,14 +85,11 @@ func EncryptJpegThumbnail(buf []byte) ([]byte, error) {
 	bufNew := make([]byte, 0x1C000)
 	copy(bufNew, buf)
 
-	// Technically random bytes, we make it deterministic here
-	randomSeed := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
-	r := &Random{
-		binary.LittleEndian.Uint32(randomSeed[0:4]),
-		binary.LittleEndian.Uint32(randomSeed[4:8]),
-		binary.LittleEndian.Uint32(randomSeed[8:12]),
-		binary.LittleEndian.Uint32(randomSeed[12:16]),
+	randomSeed, err := opts.randomBytes(0x10)
+	if err != nil {
+		return nil, err
 	}
+	r := newRandomFromSeed(randomSeed)
 
 	sha256Key := new(bytes.Buffer)
 	createKey(r, thumbnailTable, 0x10, sha256Key)
@@ -138,3 +111,54 @@ func EncryptJpegThumbnail(buf []byte) ([]byte, error) {
 
 	return bufNew, nil
 }
+
+// Returned by VerifyThumbnail when the trailer marker is missing, as for a
+// plain JPEG or a thumbnail with a stripped or damaged trailer
+var ErrThumbnailNotEncrypted = errors.New("thumbnail is not encrypted")
+
+// Returned by VerifyThumbnail when the HMAC doesn't match the thumbnail contents
+type ThumbnailMacError struct {
+	Want []byte
+	Got  []byte
+}
+
+func (e *ThumbnailMacError) Error() string {
+	return fmt.Sprintf("thumbnail hmac invalid %x != %x", e.Got, e.Want)
+}
+
+// Check the HMAC-SHA256 EncryptJpegThumbnail (or the game) added, using the key
+// derived from the stored seed
+func VerifyThumbnail(buf []byte) error {
+	if len(buf) != 0x1C000 {
+		return fmt.Errorf("invalid buf size %d != %d", len(buf), 0x1C000)
+	}
+	if !bytes.Equal(buf[0x1BF9C:(0x1BF9C+4)], []byte{0x9C, 0xBF, 0x01, 0x00}) {
+		return ErrThumbnailNotEncrypted
+	}
+
+	r := newRandomFromSeed(buf[0x1BFC0:0x1BFD0])
+
+	sha256Key := new(bytes.Buffer)
+	createKey(r, thumbnailTable, 0x10, sha256Key)
+
+	mac := hmac.New(sha256.New, sha256Key.Bytes())
+	mac.Write(buf[:0x1BF9C])
+	got := mac.Sum(nil)
+	want := buf[0x1BFA0:0x1BFC0]
+	if !hmac.Equal(got, want) {
+		return &ThumbnailMacError{Want: append([]byte{}, want...), Got: got}
+	}
+	return nil
+}
+
+// Verify and strip the trailer and padding, returns the original JPEG
+func DecryptJpegThumbnail(buf []byte) ([]byte, error) {
+	err := VerifyThumbnail(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// JPEGs end with FFD9 so only padding is removed
+	jpegBuf := bytes.TrimRight(buf[:0x1BF9C], "\x00")
+	return append([]byte{}, jpegBuf...), nil
+}
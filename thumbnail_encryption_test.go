@@ -0,0 +1,35 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVerifyThumbnail(t *testing.T) {
+	plain := testJpeg(t)
+	encrypted, err := EncryptJpegThumbnail(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyThumbnail(encrypted); err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptJpegThumbnail(encrypted)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Errorf("DecryptJpegThumbnail changed the JPEG, %v", err)
+	}
+
+	stripped := append([]byte{}, encrypted...)
+	copy(stripped[0x1BF9C:], []byte{0, 0, 0, 0})
+	if err := VerifyThumbnail(stripped); !errors.Is(err, ErrThumbnailNotEncrypted) {
+		t.Errorf("stripped marker: %v, want ErrThumbnailNotEncrypted", err)
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[100] ^= 1
+	var macErr *ThumbnailMacError
+	if err := VerifyThumbnail(tampered); !errors.As(err, &macErr) {
+		t.Errorf("tampered JPEG: %v, want *ThumbnailMacError", err)
+	}
+}
@@ -0,0 +1,194 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// How FitThumbnail shrinks a thumbnail. Quality is tried first, the other
+// steps only run when even MinQuality is too large.
+type FitOptions struct {
+	MinQuality int  // Lowest JPEG quality tried
+	MaxQuality int  // Highest JPEG quality tried
+	Chroma     bool // Average chroma over 4x4 pixels, twice as coarse as the 4:2:0 image/jpeg always uses
+	// Scales tried after that, the image is scaled down and back up so its
+	// dimensions don't change
+	Downscale []float64
+}
+
+var DefaultFitOptions = FitOptions{
+	MinQuality: 20,
+	MaxQuality: 95,
+	Chroma:     true,
+	Downscale:  []float64{0.75, 0.5},
+}
+
+type FitResult struct {
+	Quality int
+	Chroma  bool    // Chroma was reduced
+	Scale   float64 // 1 unless the image had to be downscaled
+}
+
+// Re-encode an image so it fits in a thumbnail (at most 0x1BF9C bytes) at the
+// highest quality possible, binary searching over quality
+func FitThumbnail(buf []byte, opts *FitOptions) ([]byte, *FitResult, error) {
+	if opts == nil {
+		opts = &DefaultFitOptions
+	}
+	if opts.MinQuality < 1 || opts.MaxQuality > 100 || opts.MinQuality > opts.MaxQuality {
+		return nil, nil, fmt.Errorf("invalid quality range %d-%d", opts.MinQuality, opts.MaxQuality)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, quality, err := fitQuality(img, opts)
+	if err != nil || out != nil {
+		return out, &FitResult{Quality: quality, Scale: 1}, err
+	}
+
+	if opts.Chroma {
+		img = reduceChroma(img)
+		out, quality, err = fitQuality(img, opts)
+		if err != nil || out != nil {
+			return out, &FitResult{Quality: quality, Chroma: true, Scale: 1}, err
+		}
+	}
+
+	bounds := img.Bounds()
+	for _, scale := range opts.Downscale {
+		w := int(float64(bounds.Dx()) * scale)
+		h := int(float64(bounds.Dy()) * scale)
+		if w < 1 || h < 1 {
+			continue
+		}
+		scaled := scaleImage(scaleImage(img, w, h), bounds.Dx(), bounds.Dy())
+		out, quality, err = fitQuality(scaled, opts)
+		if err != nil || out != nil {
+			return out, &FitResult{Quality: quality, Chroma: opts.Chroma, Scale: scale}, err
+		}
+	}
+
+	return nil, nil, fmt.Errorf("unable to fit thumbnail in %d bytes", thumbnailJpegMaxSize)
+}
+
+// Highest quality that fits, nil if even the minimum doesn't
+func fitQuality(img image.Image, opts *FitOptions) ([]byte, int, error) {
+	encode := func(quality int) ([]byte, error) {
+		out := &bytes.Buffer{}
+		err := jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+		return out.Bytes(), err
+	}
+
+	best, err := encode(opts.MinQuality)
+	if err != nil || len(best) > thumbnailJpegMaxSize {
+		return nil, 0, err
+	}
+
+	low := opts.MinQuality
+	high := opts.MaxQuality
+	for low < high {
+		mid := (low + high + 1) / 2
+		out, err := encode(mid)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(out) <= thumbnailJpegMaxSize {
+			low = mid
+			best = out
+		} else {
+			high = mid - 1
+		}
+	}
+	return best, low, nil
+}
+
+func reduceChroma(src image.Image) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio420)
+	blocksX := (bounds.Dx() + 3) / 4
+	blocksY := (bounds.Dy() + 3) / 4
+	cbSum := make([]int, blocksX*blocksY)
+	crSum := make([]int, blocksX*blocksY)
+	count := make([]int, blocksX*blocksY)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := src.At(x, y).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			dst.Y[dst.YOffset(x, y)] = yy
+			block := (y-bounds.Min.Y)/4*blocksX + (x-bounds.Min.X)/4
+			cbSum[block] += int(cb)
+			crSum[block] += int(cr)
+			count[block]++
+		}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		for x := bounds.Min.X; x < bounds.Max.X; x += 2 {
+			block := (y-bounds.Min.Y)/4*blocksX + (x-bounds.Min.X)/4
+			offset := dst.COffset(x, y)
+			dst.Cb[offset] = uint8(cbSum[block] / count[block])
+			dst.Cr[offset] = uint8(crSum[block] / count[block])
+		}
+	}
+	return dst
+}
+
+// Bilinear scaling
+func scaleImage(src image.Image, w int, h int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := (float64(y)+0.5)*float64(bounds.Dy())/float64(h) - 0.5
+		for x := 0; x < w; x++ {
+			sx := (float64(x)+0.5)*float64(bounds.Dx())/float64(w) - 0.5
+			x0, y0 := clampInt(int(sx), 0, bounds.Dx()-1), clampInt(int(sy), 0, bounds.Dy()-1)
+			x1, y1 := clampInt(x0+1, 0, bounds.Dx()-1), clampInt(y0+1, 0, bounds.Dy()-1)
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			if fx < 0 {
+				fx = 0
+			}
+			if fy < 0 {
+				fy = 0
+			}
+
+			var channels [4]float64
+			for _, sample := range []struct {
+				x, y   int
+				weight float64
+			}{
+				{x0, y0, (1 - fx) * (1 - fy)},
+				{x1, y0, fx * (1 - fy)},
+				{x0, y1, (1 - fx) * fy},
+				{x1, y1, fx * fy},
+			} {
+				r, g, b, a := src.At(bounds.Min.X+sample.x, bounds.Min.Y+sample.y).RGBA()
+				channels[0] += float64(r) * sample.weight
+				channels[1] += float64(g) * sample.weight
+				channels[2] += float64(b) * sample.weight
+				channels[3] += float64(a) * sample.weight
+			}
+			offset := dst.PixOffset(x, y)
+			for c := range channels {
+				dst.Pix[offset+c] = uint8(channels[c] / 257)
+			}
+		}
+	}
+	return dst
+}
+
+func clampInt(v int, low int, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
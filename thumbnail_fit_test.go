@@ -0,0 +1,150 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+)
+
+// Thumbnail sized noise, random in every channel or only in luma or chroma
+func noiseImage(noise string) image.Image {
+	r := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, THUMBNAIL_WIDTH, THUMBNAIL_HEIGHT))
+	for y := 0; y < THUMBNAIL_HEIGHT; y++ {
+		for x := 0; x < THUMBNAIL_WIDTH; x++ {
+			var c color.Color
+			switch noise {
+			case "luma":
+				v := uint8(r.Intn(256))
+				c = color.RGBA{v, v, v, 255}
+			case "chroma":
+				c = color.YCbCr{128, uint8(r.Intn(256)), uint8(r.Intn(256))}
+			default:
+				c = color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJpeg(t *testing.T, img image.Image, quality int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func checkFits(t *testing.T, out []byte) {
+	t.Helper()
+	if len(out) > thumbnailJpegMaxSize {
+		t.Fatalf("%d bytes is more than 0x%X", len(out), thumbnailJpegMaxSize)
+	}
+	info, err := InspectJpeg(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := info.ThumbnailProblems(); len(problems) != 0 {
+		t.Fatalf("fitted thumbnail has problems %v", problems)
+	}
+}
+
+func TestFitThumbnailSmall(t *testing.T) {
+	out, result, err := FitThumbnail(testJpeg(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFits(t, out)
+	if *result != (FitResult{Quality: DefaultFitOptions.MaxQuality, Scale: 1}) {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+// Noise is oversized at high quality, the search finds the last quality that fits
+func TestFitThumbnailQuality(t *testing.T) {
+	img := noiseImage("rgb")
+	if len(encodeJpeg(t, img, DefaultFitOptions.MaxQuality)) <= thumbnailJpegMaxSize {
+		t.Fatal("noise fits without lowering the quality")
+	}
+
+	out, result, err := FitThumbnail(encodeJpeg(t, img, 100), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFits(t, out)
+	if result.Chroma || result.Scale != 1 || result.Quality <= DefaultFitOptions.MinQuality || result.Quality >= DefaultFitOptions.MaxQuality {
+		t.Fatalf("got %+v", result)
+	}
+
+	// Same decoded image FitThumbnail worked on
+	decoded, err := jpeg.Decode(bytes.NewReader(encodeJpeg(t, img, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, encodeJpeg(t, decoded, result.Quality)) {
+		t.Fatalf("output isn't the image at quality %d", result.Quality)
+	}
+	if len(encodeJpeg(t, decoded, result.Quality+1)) <= thumbnailJpegMaxSize {
+		t.Fatalf("quality %d would have fit too", result.Quality+1)
+	}
+
+	if _, err := EncryptJpegThumbnail(encodeJpeg(t, img, 100)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Chroma noise only fits once the chroma is averaged
+func TestFitThumbnailChroma(t *testing.T) {
+	opts := &FitOptions{MinQuality: 90, MaxQuality: 90, Chroma: true}
+	out, result, err := FitThumbnail(encodeJpeg(t, noiseImage("chroma"), 100), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFits(t, out)
+	if *result != (FitResult{Quality: 90, Chroma: true, Scale: 1}) {
+		t.Fatalf("got %+v", result)
+	}
+
+	opts.Chroma = false
+	if _, _, err := FitThumbnail(encodeJpeg(t, noiseImage("chroma"), 100), opts); err == nil {
+		t.Fatal("chroma noise fit without reducing chroma")
+	}
+}
+
+// Luma noise isn't helped by chroma, only the second downscale fits
+func TestFitThumbnailDownscale(t *testing.T) {
+	opts := &FitOptions{MinQuality: 90, MaxQuality: 90, Chroma: true, Downscale: []float64{0.75, 0.5}}
+	out, result, err := FitThumbnail(encodeJpeg(t, noiseImage("luma"), 100), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFits(t, out)
+	if *result != (FitResult{Quality: 90, Chroma: true, Scale: 0.5}) {
+		t.Fatalf("got %+v", result)
+	}
+
+	opts.Downscale = []float64{0.75}
+	if _, _, err := FitThumbnail(encodeJpeg(t, noiseImage("luma"), 100), opts); err == nil {
+		t.Fatal("luma noise fit at a scale of 0.75")
+	}
+}
+
+func TestFitThumbnailErrors(t *testing.T) {
+	for _, opts := range []*FitOptions{
+		{MinQuality: 0, MaxQuality: 90},
+		{MinQuality: 50, MaxQuality: 101},
+		{MinQuality: 90, MaxQuality: 50},
+	} {
+		if _, _, err := FitThumbnail(testJpeg(t), opts); err == nil {
+			t.Errorf("%+v: no error", opts)
+		}
+	}
+	if _, _, err := FitThumbnail([]byte("not an image"), nil); err == nil {
+		t.Error("no error for something that isn't an image")
+	}
+}
@@ -0,0 +1,227 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// Size of course thumbnails
+const (
+	THUMBNAIL_WIDTH  = 640
+	THUMBNAIL_HEIGHT = 360
+)
+
+// Largest JPEG that fits in a thumbnail, the bytes before the 9C BF 01 00
+// marker at 0x1BF9C
+const thumbnailJpegMaxSize = 0x1BF9C
+
+// Structure of a JPEG as far as it matters for the game. Thumbnails repacked
+// with image/jpeg have always been accepted, so anything that differs from what
+// it writes (baseline, 8 bit, 4:2:0, no restart markers) is reported as a
+// possible reason the game rejects a thumbnail.
+type JpegInfo struct {
+	Width           int
+	Height          int
+	SOF             byte // Start of frame marker, 0xC0 is baseline
+	Precision       int
+	Components      []JpegComponent
+	RestartInterval int
+	AppMarkers      []byte // APPn markers present, 0xE0 is JFIF, 0xE1 EXIF
+	Scans           int
+}
+
+type JpegComponent struct {
+	Id         byte
+	Horizontal int // Sampling factors
+	Vertical   int
+}
+
+func (i *JpegInfo) Progressive() bool {
+	return i.SOF == 0xC2 || i.SOF == 0xC6 || i.SOF == 0xCA || i.SOF == 0xCE
+}
+
+func (i *JpegInfo) Arithmetic() bool {
+	return i.SOF >= 0xC9
+}
+
+// "4:2:0", "4:2:2", "4:4:4", "gray" or "other"
+func (i *JpegInfo) Subsampling() string {
+	if len(i.Components) == 1 {
+		return "gray"
+	}
+	if len(i.Components) != 3 {
+		return "other"
+	}
+	for _, c := range i.Components[1:] {
+		if c.Horizontal != 1 || c.Vertical != 1 {
+			return "other"
+		}
+	}
+	switch [2]int{i.Components[0].Horizontal, i.Components[0].Vertical} {
+	case [2]int{2, 2}:
+		return "4:2:0"
+	case [2]int{2, 1}:
+		return "4:2:2"
+	case [2]int{1, 1}:
+		return "4:4:4"
+	}
+	return "other"
+}
+
+// Reasons this JPEG might not be accepted as a course thumbnail, empty if it
+// is 640x360 and looks like what image/jpeg writes
+func (i *JpegInfo) ThumbnailProblems() []string {
+	problems := i.formatProblems()
+	if i.Width != THUMBNAIL_WIDTH || i.Height != THUMBNAIL_HEIGHT {
+		problems = append(problems, fmt.Sprintf("%dx%d instead of %dx%d", i.Width, i.Height, THUMBNAIL_WIDTH, THUMBNAIL_HEIGHT))
+	}
+	return problems
+}
+
+// ThumbnailProblems without the size. Only baseline (SOF0) counts since that
+// is all image/jpeg writes, even though extended sequential (SOF1) decodes the
+// same way.
+func (i *JpegInfo) formatProblems() []string {
+	var problems []string
+	switch {
+	case i.Progressive():
+		problems = append(problems, "progressive")
+	case i.Arithmetic():
+		problems = append(problems, "arithmetic coding")
+	case i.SOF == 0xC3:
+		problems = append(problems, "lossless")
+	case i.SOF == 0xC1:
+		problems = append(problems, "extended sequential")
+	case i.SOF != 0xC0:
+		problems = append(problems, fmt.Sprintf("unsupported frame type 0x%02X", i.SOF))
+	}
+	if i.Precision != 8 {
+		problems = append(problems, fmt.Sprintf("%d bit precision", i.Precision))
+	}
+	if subsampling := i.Subsampling(); subsampling != "4:2:0" && subsampling != "gray" {
+		problems = append(problems, fmt.Sprintf("%s chroma subsampling", subsampling))
+	}
+	if i.RestartInterval != 0 {
+		problems = append(problems, fmt.Sprintf("restart interval %d", i.RestartInterval))
+	}
+	return problems
+}
+
+func InspectJpeg(buf []byte) (*JpegInfo, error) {
+	if len(buf) < 4 || buf[0] != 0xFF || buf[1] != 0xD8 {
+		return nil, fmt.Errorf("not a jpeg")
+	}
+
+	info := &JpegInfo{}
+	pos := 2
+	for {
+		// Find the next marker, skipping fill bytes
+		if pos+2 > len(buf) || buf[pos] != 0xFF {
+			return nil, fmt.Errorf("expected marker at 0x%x", pos)
+		}
+		for pos < len(buf) && buf[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(buf) {
+			return nil, fmt.Errorf("unexpected end of jpeg")
+		}
+		marker := buf[pos]
+		pos++
+
+		if marker == 0xD9 {
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			continue
+		}
+
+		if pos+2 > len(buf) {
+			return nil, fmt.Errorf("unexpected end of jpeg")
+		}
+		length := int(binary.BigEndian.Uint16(buf[pos:]))
+		if length < 2 || pos+length > len(buf) {
+			return nil, fmt.Errorf("invalid length for marker 0x%02X", marker)
+		}
+		segment := buf[pos+2 : pos+length]
+		pos += length
+
+		switch {
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC:
+			if len(segment) < 6 {
+				return nil, fmt.Errorf("invalid start of frame")
+			}
+			info.SOF = marker
+			info.Precision = int(segment[0])
+			info.Height = int(binary.BigEndian.Uint16(segment[1:]))
+			info.Width = int(binary.BigEndian.Uint16(segment[3:]))
+			count := int(segment[5])
+			if len(segment) < 6+count*3 {
+				return nil, fmt.Errorf("invalid start of frame")
+			}
+			info.Components = make([]JpegComponent, count)
+			for c := range info.Components {
+				component := segment[6+c*3:]
+				info.Components[c] = JpegComponent{component[0], int(component[1] >> 4), int(component[1] & 0xF)}
+			}
+		case marker == 0xDD:
+			if len(segment) < 2 {
+				return nil, fmt.Errorf("invalid restart interval")
+			}
+			info.RestartInterval = int(binary.BigEndian.Uint16(segment))
+		case marker >= 0xE0 && marker <= 0xEF:
+			info.AppMarkers = append(info.AppMarkers, marker)
+		case marker == 0xDA:
+			info.Scans++
+			// Skip entropy coded data up to the next marker that isn't a
+			// stuffed byte or a restart marker
+			for pos+1 < len(buf) && !(buf[pos] == 0xFF && buf[pos+1] != 0x00 && (buf[pos+1] < 0xD0 || buf[pos+1] > 0xD7)) {
+				pos++
+			}
+		}
+	}
+
+	if info.SOF == 0 {
+		return nil, fmt.Errorf("jpeg has no frame")
+	}
+	return info, nil
+}
+
+// Re-encode only if the JPEG has something the game might not accept, returns
+// the reasons it was re-encoded. Images that aren't JPEGs are always re-encoded
+// and images that aren't 640x360 are scaled to it.
+func RepackThumbnailIfNeeded(buf []byte) ([]byte, []string, error) {
+	return repackJpeg(buf, true)
+}
+
+func repackJpeg(buf []byte, checkSize bool) ([]byte, []string, error) {
+	info, err := InspectJpeg(buf)
+	var problems []string
+	if err != nil {
+		problems = []string{err.Error()}
+	} else if checkSize {
+		problems = info.ThumbnailProblems()
+	} else {
+		problems = info.formatProblems()
+	}
+	if len(problems) == 0 {
+		return buf, nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, problems, err
+	}
+	if checkSize && (img.Bounds().Dx() != THUMBNAIL_WIDTH || img.Bounds().Dy() != THUMBNAIL_HEIGHT) {
+		img = scaleImage(img, THUMBNAIL_WIDTH, THUMBNAIL_HEIGHT)
+	}
+
+	out := &bytes.Buffer{}
+	err = jpeg.Encode(out, img, &jpeg.Options{Quality: 65})
+	if err != nil {
+		return nil, problems, err
+	}
+	return out.Bytes(), problems, nil
+}
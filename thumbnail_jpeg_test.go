@@ -0,0 +1,57 @@
+package smm2_parsing
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+func TestThumbnailProblems(t *testing.T) {
+	info, err := InspectJpeg(testJpeg(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := info.ThumbnailProblems(); len(problems) != 0 {
+		t.Errorf("image/jpeg output has problems %v", problems)
+	}
+
+	for _, test := range []struct {
+		change  func(i *JpegInfo)
+		problem string
+	}{
+		{func(i *JpegInfo) { i.Width = 320 }, "320x360"},
+		{func(i *JpegInfo) { i.Height = 720 }, "640x720"},
+		{func(i *JpegInfo) { i.SOF = 0xC1 }, "extended sequential"},
+		{func(i *JpegInfo) { i.SOF = 0xC2 }, "progressive"},
+		{func(i *JpegInfo) { i.RestartInterval = 4 }, "restart interval"},
+	} {
+		changed := *info
+		test.change(&changed)
+		problems := strings.Join(changed.ThumbnailProblems(), ", ")
+		if !strings.Contains(problems, test.problem) {
+			t.Errorf("problems %q, want %q", problems, test.problem)
+		}
+	}
+}
+
+func TestRepackThumbnailSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 320, 180)), nil); err != nil {
+		t.Fatal(err)
+	}
+	out, problems, err := RepackThumbnailIfNeeded(buf.Bytes())
+	if err != nil || len(problems) == 0 {
+		t.Fatalf("problems %v, %v", problems, err)
+	}
+	info, err := InspectJpeg(out)
+	if err != nil || info.Width != THUMBNAIL_WIDTH || info.Height != THUMBNAIL_HEIGHT {
+		t.Errorf("repacked to %v, %v", info, err)
+	}
+
+	world, err := RepackWorldThumbnailSilly(buf.Bytes())
+	if err != nil || !bytes.Equal(world, buf.Bytes()) {
+		t.Errorf("world thumbnail repacked, %v", err)
+	}
+}